@@ -0,0 +1,99 @@
+package main
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+	"github.com/akayj/tmux-touchpad-battery/internal/events"
+	"github.com/akayj/tmux-touchpad-battery/internal/notify"
+	"github.com/akayj/tmux-touchpad-battery/internal/snapshot"
+	"github.com/akayj/tmux-touchpad-battery/internal/state"
+	"github.com/akayj/tmux-touchpad-battery/internal/system"
+	"github.com/akayj/tmux-touchpad-battery/internal/tmux"
+	"github.com/akayj/tmux-touchpad-battery/internal/webhook"
+)
+
+// alertStateFile tmux 提醒状态文件名
+const alertStateFile = "alert.json"
+
+// alertTmux 触摸板电量跌破 @tpb_stress_threshold 时按 @tpb_alert_mode 提醒
+// 每次跌破只提醒一次，状态保存在状态目录中，避免每次重绘都重复提醒
+func alertTmux(config *tmux.Config, info *battery.BatteryInfo) {
+	switch config.AlertMode {
+	case notify.TmuxModeMessage, notify.TmuxModePopup, notify.TmuxModeBell:
+	default:
+		return
+	}
+
+	dir, err := state.Dir()
+	if err != nil {
+		return
+	}
+	path := filepath.Join(dir, alertStateFile)
+
+	// 先保存状态再发送通知，通知期间的重绘不会重复提醒
+	deferred := &deferredNotifier{}
+	alerter := notify.NewAlerter(deferred, []int{config.StressThreshold})
+	alerter.Snooze = 0
+	alerter.SnoozePath, _ = notify.SnoozePath()
+	if err := alerter.LoadState(path); err != nil {
+		return
+	}
+
+	_ = alerter.Handle(&snapshot.Snapshot{Time: time.Now(), Devices: []*battery.BatteryInfo{info}})
+	if err := alerter.SaveState(path); err != nil {
+		return
+	}
+	deferred.flush(notify.Tmux{Mode: config.AlertMode})
+}
+
+// deferredNotifier 暂存通知，由 flush 统一发送
+type deferredNotifier struct {
+	pending []notify.Notification
+}
+
+// Notify 实现 notify.Notifier
+func (d *deferredNotifier) Notify(n notify.Notification) error {
+	d.pending = append(d.pending, n)
+	return nil
+}
+
+// flush 通过 notifier 发送暂存的通知
+func (d *deferredNotifier) flush(notifier notify.Notifier) {
+	for _, n := range d.pending {
+		_ = notifier.Notify(n)
+	}
+	d.pending = nil
+}
+
+// observeEvents 与上一次状态栏重绘时的采样比较，运行用户配置的事件钩子和 webhook
+// 没有配置钩子和 webhook 时直接返回
+func observeEvents(config *tmux.Config, devices []*battery.BatteryInfo, devicesErr error, systemInfo *system.SystemInfo) {
+	path, err := events.StatePath()
+	if err != nil {
+		return
+	}
+
+	var handlers []events.Handler
+	if wh, err := webhook.FromConfig(config); err == nil && wh != nil {
+		// 状态栏重绘不等待重试，也不等待慢速的服务器，未送达的请求留在队列中由之后的重绘补发
+		wh.Attempts = 1
+		wh.Deadline = webhook.RedrawDeadline
+		handlers = append(handlers, wh)
+	}
+
+	watcher := events.NewWatcher(config, path, handlers...)
+	if watcher == nil {
+		return
+	}
+	// 状态栏重绘不等待钩子命令结束
+	watcher.DetachHooks()
+
+	_ = watcher.Handle(&snapshot.Snapshot{
+		Time:       time.Now(),
+		Devices:    devices,
+		DevicesErr: devicesErr,
+		System:     systemInfo,
+	})
+}
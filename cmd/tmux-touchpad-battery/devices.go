@@ -0,0 +1,45 @@
+package main
+
+import (
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+	"github.com/akayj/tmux-touchpad-battery/internal/display"
+	"github.com/akayj/tmux-touchpad-battery/internal/presence"
+	"github.com/akayj/tmux-touchpad-battery/internal/tmux"
+)
+
+// applyLastSeen 启用 @tpb_show_disconnected 时用本次获取的设备列表更新连接记录，
+// 触摸板断开时在格式化器中设置它最后一次出现的状态
+func applyLastSeen(config *tmux.Config, bf *display.BatteryFormatter, info *battery.BatteryInfo, devices []*battery.BatteryInfo, devicesErr error) {
+	if !config.ShowDisconnected {
+		return
+	}
+
+	tracker, err := presence.Open()
+	if err != nil {
+		return
+	}
+	if devicesErr == nil {
+		_ = tracker.Update(devices, time.Now())
+	}
+
+	if info == nil || !info.Available {
+		if r, err := tracker.LastDisconnected(battery.ClassTrackpad); err == nil {
+			bf.SetLastSeen(r)
+		}
+	}
+}
+
+// batterySource 返回状态栏的电池片段来源
+// 设置了 @tpb_devices 或 @tpb_exclude 时按筛选后的顺序显示所有设备，否则只显示触摸板；
+// 多设备时按设备读取并保存上一次显示的级别
+func batterySource(config *tmux.Config, bf *display.BatteryFormatter, list *display.DeviceListFormatter, devices []*battery.BatteryInfo) display.BatterySource {
+	if !config.MultiDevice() {
+		return bf
+	}
+	saveLevels := restoreDeviceLevels(list)
+	list.SetDevices(devices)
+	saveLevels()
+	return list
+}
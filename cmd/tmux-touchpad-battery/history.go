@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+	"github.com/akayj/tmux-touchpad-battery/internal/display"
+	"github.com/akayj/tmux-touchpad-battery/internal/history"
+	"github.com/akayj/tmux-touchpad-battery/internal/snapshot"
+	"github.com/akayj/tmux-touchpad-battery/internal/system"
+	"github.com/akayj/tmux-touchpad-battery/internal/tmux"
+)
+
+// runHistory 查询电量历史记录
+func runHistory(args []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	var (
+		device = fs.String("device", "", "按设备名称或序列号过滤")
+		since  = fs.String("since", "24h", "起始时间：相对时长（如 2h、7d）或 RFC3339 / 2006-01-02 格式")
+		until  = fs.String("until", "", "结束时间，格式同 --since（默认: 现在）")
+		format = fs.String("format", "table", "输出格式: table, json, csv")
+	)
+	_ = fs.Parse(args)
+
+	now := time.Now()
+	query := history.Query{Device: *device}
+
+	var err error
+	if query.Since, err = parseHistoryTime(*since, now); err != nil {
+		fmt.Fprintf(os.Stderr, "无效的 --since: %v\n", err)
+		os.Exit(2)
+	}
+	if query.Until, err = parseHistoryTime(*until, now); err != nil {
+		fmt.Fprintf(os.Stderr, "无效的 --until: %v\n", err)
+		os.Exit(2)
+	}
+
+	store, err := history.Open()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "打开历史记录失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	samples, err := store.Query(query)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "查询历史记录失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if samples == nil {
+			samples = []history.Sample{}
+		}
+		err = enc.Encode(samples)
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		_ = w.Write([]string{"time", "device", "serial", "percent", "charging"})
+		for _, s := range samples {
+			_ = w.Write([]string{
+				s.Time.Format(time.RFC3339),
+				s.Device,
+				s.Serial,
+				strconv.Itoa(s.Percent),
+				strconv.FormatBool(s.Charging),
+			})
+		}
+		w.Flush()
+		err = w.Error()
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "时间\t设备\t电量\t充电")
+		for _, s := range samples {
+			charging := ""
+			if s.Charging {
+				charging = "⚡"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d%%\t%s\n", s.Time.Local().Format("2006-01-02 15:04"), s.Device, s.Percent, charging)
+		}
+		err = w.Flush()
+	default:
+		fmt.Fprintf(os.Stderr, "不支持的输出格式: %s\n", *format)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// parseHistoryTime 解析时间参数，空字符串表示不限制
+// 支持相对时长（2h、30m，以及按天计的 7d）和绝对时间（RFC3339、2006-01-02）
+func parseHistoryTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if n := len(value); n > 1 && value[n-1] == 'd' {
+		if days, err := strconv.Atoi(value[:n-1]); err == nil {
+			return now.Add(-time.Duration(days) * 24 * time.Hour), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("无法解析时间 %q", value)
+}
+
+// recordHistory 在 tmux 调用时按限频记录设备电量和 CPU 使用率
+// 未到记录间隔时直接返回；设备获取失败时 devices 为空，仍然记录 CPU 使用率
+func recordHistory(config *tmux.Config, devices []*battery.BatteryInfo, systemInfo *system.SystemInfo) {
+	if !config.History {
+		return
+	}
+
+	store, err := history.Open()
+	if err != nil {
+		return
+	}
+	store.MinInterval = time.Duration(config.HistoryInterval) * time.Second
+
+	now := time.Now()
+	if !store.Due(now) {
+		return
+	}
+
+	_ = store.Record(history.SamplesFromSnapshot(&snapshot.Snapshot{Time: now, Devices: devices, System: systemInfo}))
+}
+
+// applyETA 显示格式引用了 {eta} 时，根据历史记录估算触摸板的剩余时间
+func applyETA(bf *display.BatteryFormatter, devices []*battery.BatteryInfo) {
+	if bf.UsesETA() {
+		bf.SetETA(estimateETA(devices))
+	}
+}
+
+// estimateETA 估算触摸板的剩余时间，无法估算时返回 0
+func estimateETA(devices []*battery.BatteryInfo) time.Duration {
+	store, err := history.Open()
+	if err != nil {
+		return 0
+	}
+
+	device := touchpadDevice(devices)
+	if device == "" {
+		return 0
+	}
+
+	eta, ok := store.ETA(device, time.Now())
+	if !ok {
+		return 0
+	}
+	return eta
+}
+
+// touchpadDevice 返回触摸板在历史记录中的标识，没有可用的触摸板时返回空
+// 与状态栏显示的触摸板为同一个设备（FindTouchpad）
+func touchpadDevice(devices []*battery.BatteryInfo) string {
+	touchpad := battery.FindTouchpad(devices)
+	if !touchpad.Available {
+		return ""
+	}
+	if touchpad.Serial != "" {
+		return touchpad.Serial
+	}
+	return touchpad.Name
+}
+
+// newSparklines 按 @tpb_sparkline 创建迷你图格式化器，数据来自历史记录
+func newSparklines(config *tmux.Config, devices []*battery.BatteryInfo) []display.SegmentFormatter {
+	if config.Sparkline == "" {
+		return nil
+	}
+
+	store, err := history.Open()
+	if err != nil {
+		return nil
+	}
+
+	// 较早的记录压缩后每个时间桶只有一条，只读取最近 SparklineLength 个时间桶内的记录
+	step := max(time.Duration(config.HistoryInterval)*time.Second, history.DefaultCompactBucket)
+	since := time.Now().Add(-time.Duration(config.SparklineLength) * step)
+
+	var formatters []display.SegmentFormatter
+	for _, source := range strings.Split(config.Sparkline, ",") {
+		source = strings.TrimSpace(source)
+
+		var device string
+		switch source {
+		case display.SparklineCPU:
+			device = history.DeviceCPU
+		case display.SparklineBattery:
+			device = touchpadDevice(devices)
+		}
+		if device == "" {
+			continue
+		}
+
+		values, err := store.Series(device, config.SparklineLength, since)
+		if err != nil {
+			continue
+		}
+
+		f := display.NewSparklineFormatter(config, source)
+		f.SetValues(values)
+		formatters = append(formatters, f)
+	}
+	return formatters
+}
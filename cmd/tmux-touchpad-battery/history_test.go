@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+)
+
+func TestTouchpadDevice(t *testing.T) {
+	mouse := &battery.BatteryInfo{Name: "MX Master 3", Serial: "MX-1", Kind: battery.ClassMouse, Available: true}
+	trackpad := &battery.BatteryInfo{Name: "Magic Trackpad", Serial: "TP-1", Available: true}
+	unnamed := &battery.BatteryInfo{Name: "Magic Trackpad", Available: true}
+
+	tests := []struct {
+		name    string
+		devices []*battery.BatteryInfo
+		want    string
+	}{
+		{"第一个设备不是触摸板", []*battery.BatteryInfo{mouse, trackpad}, "TP-1"},
+		{"没有序列号时使用名称", []*battery.BatteryInfo{mouse, unnamed}, "Magic Trackpad"},
+		{"没有触摸板", []*battery.BatteryInfo{mouse}, ""},
+		{"没有设备", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := touchpadDevice(tt.devices); got != tt.want {
+				t.Errorf("touchpadDevice() = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/display"
+	"github.com/akayj/tmux-touchpad-battery/internal/state"
+)
+
+// 级别回差状态文件名，多设备模式下按设备保存
+const (
+	levelStateFile   = "level.json"
+	deviceLevelsFile = "levels.json"
+)
+
+// restoreLevel 启用级别回差时读取上一次显示的级别，需要在 SetBatteryInfo 之前调用
+// 返回的函数在 SetBatteryInfo 之后调用，保存本次显示的级别供下一次状态栏重绘使用
+func restoreLevel(bf *display.BatteryFormatter) func() {
+	if !bf.UsesHysteresis() {
+		return func() {}
+	}
+
+	dir, err := state.Dir()
+	if err != nil {
+		return func() {}
+	}
+	path := filepath.Join(dir, levelStateFile)
+
+	saved, err := os.ReadFile(path)
+	if err == nil {
+		var st display.LevelState
+		if err := json.Unmarshal(saved, &st); err == nil {
+			bf.SetLevelState(&st)
+		}
+	}
+
+	return func() {
+		st := bf.LevelState()
+		if st == nil {
+			return
+		}
+		_ = saveLevelState(path, st, saved)
+	}
+}
+
+// restoreDeviceLevels 读取多设备模式下每个设备上一次显示的级别，需要在 SetDevices 之前调用
+// 返回的函数在 SetDevices 之后调用，保存启用了级别回差的设备本次显示的级别
+func restoreDeviceLevels(list *display.DeviceListFormatter) func() {
+	dir, err := state.Dir()
+	if err != nil {
+		return func() {}
+	}
+	path := filepath.Join(dir, deviceLevelsFile)
+
+	saved, err := os.ReadFile(path)
+	if err == nil {
+		var states map[string]*display.LevelState
+		if err := json.Unmarshal(saved, &states); err == nil {
+			list.SetLevelStates(states)
+		}
+	}
+
+	return func() {
+		states := list.LevelStates()
+		if len(states) == 0 {
+			return
+		}
+		_ = saveLevelState(path, states, saved)
+	}
+}
+
+// saveLevelState 保存级别状态供下一次状态栏重绘使用
+// 级别在阈值之间保持不变时内容与读取时相同，不重写文件
+func saveLevelState(path string, st any, saved []byte) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(data, saved) {
+		return nil
+	}
+	return state.WriteFile(path, data)
+}
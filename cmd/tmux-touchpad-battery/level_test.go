@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/display"
+)
+
+func TestSaveLevelStateUnchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), levelStateFile)
+	st := &display.LevelState{Level: display.LevelStress}
+
+	if err := saveLevelState(path, st, nil); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 与读取时的内容相同时不重写文件
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveLevelState(path, st, saved); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("状态没有变化时不应写入文件: %v", err)
+	}
+
+	// 级别变化时写入
+	if err := saveLevelState(path, &display.LevelState{Level: display.LevelMedium}, saved); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("状态变化时应该写入文件: %v", err)
+	}
+}
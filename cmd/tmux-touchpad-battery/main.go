@@ -1,24 +1,50 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/akayj/tmux-touchpad-battery/internal/battery"
 	"github.com/akayj/tmux-touchpad-battery/internal/display"
+	"github.com/akayj/tmux-touchpad-battery/internal/history"
+	"github.com/akayj/tmux-touchpad-battery/internal/presence"
+	"github.com/akayj/tmux-touchpad-battery/internal/report"
+	"github.com/akayj/tmux-touchpad-battery/internal/snapshot"
 	"github.com/akayj/tmux-touchpad-battery/internal/system"
 	"github.com/akayj/tmux-touchpad-battery/internal/tmux"
 	"github.com/akayj/tmux-touchpad-battery/internal/ui"
 )
 
 func main() {
+	// 子命令
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		case "history":
+			runHistory(os.Args[2:])
+			return
+		case "snooze":
+			runSnooze(os.Args[2:])
+			return
+		}
+	}
+
 	var (
 		showStatus = flag.Bool("status", false, "显示电池状态")
 		showUI     = flag.Bool("ui", false, "启动交互式 UI")
 		showHelp   = flag.Bool("help", false, "显示帮助信息")
+		output     = flag.String("output", "tmux", "输出格式: tmux, json, yaml, i3bar, waybar, polybar, prompt")
+		shell      = flag.String("shell", "", "prompt 模式的目标: zsh, bash, fish, plain（默认根据 $SHELL 判断）")
+		interval   = flag.Duration("interval", 5*time.Second, "i3bar 模式的刷新间隔")
 	)
 	flag.Parse()
 
@@ -37,8 +63,20 @@ func main() {
 		return
 	}
 
-	// 默认行为：输出 tmux 格式
-	outputTmuxFormat()
+	switch *output {
+	case "json", "yaml":
+		outputReport(*output)
+	case "i3bar", "waybar", "polybar":
+		outputBar(*output, *interval)
+	case "prompt":
+		outputPrompt(*shell)
+	case "tmux", "":
+		// 默认行为：输出 tmux 格式
+		outputTmuxFormat()
+	default:
+		fmt.Fprintf(os.Stderr, "不支持的输出格式: %s\n", *output)
+		os.Exit(2)
+	}
 }
 
 func printHelp() {
@@ -48,24 +86,88 @@ func printHelp() {
 	fmt.Println("  tmux-touchpad-battery -status   显示电池状态")
 	fmt.Println("  tmux-touchpad-battery -ui       启动交互式 UI")
 	fmt.Println("  tmux-touchpad-battery -help     显示此帮助信息")
+	fmt.Println("  tmux-touchpad-battery --output json|yaml  输出机器可读的完整状态")
+	fmt.Println("  tmux-touchpad-battery --output i3bar      以 i3bar 协议持续输出 (-interval 设置刷新间隔)")
+	fmt.Println("  tmux-touchpad-battery --output waybar     输出 Waybar 自定义模块 JSON")
+	fmt.Println("  tmux-touchpad-battery --output polybar    输出带 Polybar 格式标签的文本")
+	fmt.Println("  tmux-touchpad-battery --output prompt -shell zsh|bash|fish|plain  输出 shell 提示符片段")
+	fmt.Println("  tmux-touchpad-battery serve --metrics [--listen addr]  常驻并通过 HTTP 导出 Prometheus 指标")
+	fmt.Println("  tmux-touchpad-battery serve --textfile path.prom       常驻并写入 node_exporter textfile")
+	fmt.Println("  tmux-touchpad-battery serve --notify [--notify-thresholds 20,10,5]  常驻并在低电量时发送桌面通知")
+	fmt.Println("  tmux-touchpad-battery serve --mqtt tcp://host:1883  常驻并发布到 MQTT（支持 Home Assistant discovery）")
+	fmt.Println("  tmux-touchpad-battery history [--device name] [--since 24h] [--until time] [--format table|json|csv]")
+	fmt.Println("                                  查询电量历史记录")
+	fmt.Println("  tmux-touchpad-battery snooze [1h|off]  暂停或恢复低电量提醒和桌面通知")
 	fmt.Println()
 	fmt.Println("配置选项:")
+	fmt.Println("  @tpb_theme               配色主题 (默认: 'default')")
+	fmt.Println("                           可选: " + strings.Join(display.ThemeNames(), ", "))
 	fmt.Println("  @tpb_percent_prefix      显示前缀 (默认: 'Touchpad:')")
 	fmt.Println("  @tpb_percent_suffix      显示后缀 (默认: '%')")
+	fmt.Println("  @tpb_format              电池显示格式 (默认: '{prefix}{percent}{suffix}{icon}')")
+	fmt.Println("                           {eta} 为根据历史估算的剩余时间，如 '{prefix}{percent}{suffix} ({eta})'")
 	fmt.Println("  @tpb_color_charging      充电时颜色 (默认: 'green')")
+	fmt.Println("  @tpb_color_full          充满并接通电源时颜色 (默认: 'cyan')")
 	fmt.Println("  @tpb_color_high          高电量颜色 (默认: 'white')")
 	fmt.Println("  @tpb_color_medium        中等电量颜色 (默认: 'yellow')")
 	fmt.Println("  @tpb_color_stress        低电量颜色 (默认: 'red')")
+	fmt.Println("  @tpb_color_cpu           CPU 信息颜色 (默认: 'white')")
+	fmt.Println("  @tpb_color_gpu           GPU 信息颜色 (默认: 'white')")
+	fmt.Println("  @tpb_color_title_fg/_bg  UI 标题颜色 (默认: '#FAFAFA' / '#7D56F4')")
+	fmt.Println("  @tpb_color_detail        UI 详情标签颜色 (默认: '#888888')")
+	fmt.Println("  @tpb_color_muted         UI 次要文字颜色 (默认: '#666666')")
+	fmt.Println("  @tpb_color_error         UI 错误颜色 (默认: '#FF0000')")
+	fmt.Println("  @tpb_color_warning       UI 警告颜色 (默认: '#FFAA00')")
 	fmt.Println("  @tpb_stress_threshold    低电量阈值 (默认: 30)")
 	fmt.Println("  @tpb_medium_threshold    中等电量阈值 (默认: 80)")
 	fmt.Println("  @tpb_not_show_threshold  不显示阈值 (默认: 100)")
 	fmt.Println("  @tpb_blink_on_low_battery 低电量时闪烁 (默认: 'off')")
+	fmt.Println("  @tpb_level_hysteresis    电量回升时切换级别所需超过阈值的百分点，0 表示不启用 (默认: 0)")
+	fmt.Println("  @tpb_level_min_duration  新级别持续多少秒后无论回差都切换，0 表示不启用 (默认: 0)")
+	fmt.Println("  @tpb_critical_threshold  battery.critical 事件阈值 (默认: 10)")
+	fmt.Println("  @tpb_full_threshold      battery.full 事件阈值 (默认: 100)")
+	fmt.Println("  @tpb_cpu_high_threshold  cpu.high 事件阈值 (默认: 90)")
+	fmt.Println("  @tpb_hook                所有事件运行的命令 (默认: '')")
+	fmt.Println("  @tpb_hook_<event>        指定事件运行的命令，如 @tpb_hook_battery_low (默认: '')")
+	fmt.Println("  @tpb_webhook_url         事件 webhook URL，多个用逗号分隔 (默认: '')")
+	fmt.Println("  @tpb_webhook_format      webhook 格式 generic/slack (默认: 'generic')")
+	fmt.Println("  @tpb_webhook_events      发送 webhook 的事件 (默认: 'battery.low,battery.critical')")
+	fmt.Println("  @tpb_alert_mode          跌破低电量阈值时的提醒 off/message/popup/bell (默认: 'off')")
+	fmt.Println("  @tpb_color_mode          颜色模式 threshold/gradient (默认: 'threshold')")
+	fmt.Println("  @tpb_gradient_stops      渐变颜色节点 (默认: 'red@10,yellow@50,green@90')")
+	fmt.Println("  @tpb_true_color          渐变真彩色 on/off/auto (默认: 'auto')")
+	fmt.Println("  @tpb_style_charging      充电时完整样式，如 'fg=green,bold' (默认: '')")
+	fmt.Println("  @tpb_style_full          充满并接通电源时完整样式 (默认: '')")
+	fmt.Println("  @tpb_style_high          高电量完整样式 (默认: '')")
+	fmt.Println("  @tpb_style_medium        中等电量完整样式 (默认: '')")
+	fmt.Println("  @tpb_style_stress        低电量完整样式 (默认: '')")
 	fmt.Println("  @tpb_charging_icon       充电图标 (默认: '⚡')")
 	fmt.Println("  @tpb_show_charging_icon  显示充电图标 (默认: 'on')")
+	fmt.Println("  @tpb_full_icon           充满并接通电源时的图标 (默认: '🔌')")
+	fmt.Println("  @tpb_show_full           充满并接通电源时显示电量 (默认: 'on')")
+	fmt.Println("  @tpb_powerline           启用 powerline 色块 (默认: 'off')")
+	fmt.Println("  @tpb_powerline_side      所在状态栏 left/right (默认: 'right')")
+	fmt.Println("  @tpb_powerline_left_separator  左分隔符 (默认: '\ue0b2')")
+	fmt.Println("  @tpb_powerline_right_separator 右分隔符 (默认: '\ue0b0')")
+	fmt.Println("  @tpb_powerline_status_bg 相邻状态栏背景色 (默认: 'default')")
+	fmt.Println("  @tpb_powerline_fg        电池色块文字颜色 (默认: 'black')")
+	fmt.Println("  @tpb_powerline_cpu_bg    CPU 色块背景色 (默认: 'colour238')")
+	fmt.Println("  @tpb_powerline_gpu_bg    GPU 色块背景色 (默认: 'colour236')")
 	fmt.Println("  @tpb_show_cpu_info       显示 CPU 信息 (默认: 'on')")
 	fmt.Println("  @tpb_show_gpu_info       显示 GPU 信息 (默认: 'on')")
 	fmt.Println("  @tpb_system_info_prefix  系统信息前缀 (默认: '')")
 	fmt.Println("  @tpb_system_info_suffix  系统信息后缀 (默认: '')")
+	fmt.Println("  @tpb_history             记录电量历史 (默认: 'on')")
+	fmt.Println("  @tpb_history_interval    历史记录最小间隔秒数 (默认: 60)")
+	fmt.Println("  @tpb_show_disconnected   触摸板断开时显示最后状态 (默认: 'off')")
+	fmt.Println("  @tpb_disconnected_format 断开时的显示格式 (默认: '{prefix}—({ago})')")
+	fmt.Println("  @tpb_device_<key>_<field> 按设备类别 (trackpad/mouse/keyboard) 或名称覆盖配置")
+	fmt.Println("                           field: prefix、suffix、stress_threshold、medium_threshold、")
+	fmt.Println("                           not_show_threshold，match 为名称 glob")
+	fmt.Println("  @tpb_devices             按顺序显示的设备，类别或名称 glob，如 'trackpad,keyboard' (默认: '')")
+	fmt.Println("  @tpb_exclude             不显示的设备，如 'AirPods*' (默认: '')")
+	fmt.Println("  @tpb_sparkline           显示迷你图 battery/cpu/battery,cpu (默认: '')")
+	fmt.Println("  @tpb_sparkline_length    迷你图记录条数 (默认: 10)")
 }
 
 func runUI() {
@@ -79,11 +181,20 @@ func runUI() {
 
 func showBatteryStatus() {
 	config := tmux.GetConfig()
+	if err := display.ApplyTheme(config); err != nil {
+		fmt.Printf("配置错误: %v\n", err)
+	}
 	batteryFormatter := display.NewBatteryFormatter(config)
 	systemFormatter := display.NewSystemFormatter(config)
 
+	// 报告无效的配置项
+	for _, err := range batteryFormatter.Errors() {
+		fmt.Printf("配置错误: %v\n", err)
+	}
+
 	// 获取电池信息
-	batteryInfo, err := battery.GetTouchpadBatteryInfo()
+	devices, devicesErr := battery.GetDevices()
+	batteryInfo, err := battery.TouchpadFromDevices(devices, devicesErr)
 	if err != nil {
 		fmt.Printf("获取电池信息失败: %v\n", err)
 		os.Exit(1)
@@ -99,8 +210,20 @@ func showBatteryStatus() {
 	if batteryInfo.Available {
 		fmt.Printf("电池电量: %d%%\n", batteryInfo.Percentage)
 		fmt.Printf("充电状态: %v\n", batteryInfo.IsCharging)
+		if eta := estimateETA(devices); eta > 0 && !batteryInfo.IsCharging {
+			fmt.Printf("预计剩余时间: %s\n", history.FormatETA(eta))
+			batteryFormatter.SetETA(eta)
+		}
 		batteryFormatter.SetBatteryInfo(batteryInfo)
 		fmt.Printf("电池格式化输出: %s\n", batteryFormatter.FormatWithStyle())
+	} else {
+		fmt.Println("触摸板未连接")
+		if tracker, err := presence.Open(); err == nil {
+			if r, err := tracker.LastDisconnected(battery.ClassTrackpad); err == nil && r != nil {
+				fmt.Printf("最后出现: %s（%s，电量 %d%%）\n",
+					r.LastSeen.Local().Format("2006-01-02 15:04"), presence.FormatAgo(time.Since(r.LastSeen)), r.Percentage)
+			}
+		}
 	}
 
 	if systemInfo.Available {
@@ -109,15 +232,23 @@ func showBatteryStatus() {
 		systemFormatter.SetSystemInfo(systemInfo)
 		fmt.Printf("系统格式化输出: %s\n", systemFormatter.FormatWithStyle())
 	}
+
+	if config.Powerline {
+		powerlineFormatter := display.NewPowerlineFormatter(config, batteryFormatter, systemFormatter)
+		fmt.Printf("Powerline 输出: %s\n", powerlineFormatter.FormatWithStyle())
+	}
 }
 
 func outputTmuxFormat() {
 	config := tmux.GetConfig()
+	// 主题无效时回退到默认主题
+	_ = display.ApplyTheme(config)
 	batteryFormatter := display.NewBatteryFormatter(config)
 	systemFormatter := display.NewSystemFormatter(config)
 
-	// 获取电池信息
-	batteryInfo, err := battery.GetTouchpadBatteryInfo()
+	// 获取电池信息，设备列表只获取一次，历史记录、事件和多设备显示共用
+	devices, devicesErr := battery.GetDevices()
+	batteryInfo, err := battery.TouchpadFromDevices(devices, devicesErr)
 	if err != nil {
 		// 静默失败，不输出任何内容
 		return
@@ -130,30 +261,122 @@ func outputTmuxFormat() {
 		return
 	}
 
-	// 格式化输出
+	// 输出后按限频记录电量和 CPU 历史，检查是否需要低电量提醒，并运行事件钩子
+	defer recordHistory(config, devices, systemInfo)
+	defer alertTmux(config, batteryInfo)
+	defer observeEvents(config, devices, devicesErr, systemInfo)
+
+	// 格式化输出，启用级别回差时读取并保存上一次显示的级别
+	saveLevel := restoreLevel(batteryFormatter)
 	batteryFormatter.SetBatteryInfo(batteryInfo)
-	batteryOutput := batteryFormatter.Format()
+	saveLevel()
+	applyETA(batteryFormatter, devices)
+	applyLastSeen(config, batteryFormatter, batteryInfo, devices, devicesErr)
 	systemFormatter.SetSystemInfo(systemInfo)
-	systemOutput := systemFormatter.Format()
 
-	// 如果两个输出都为空，则不输出任何内容
-	if batteryOutput == "" && systemOutput == "" {
+	source := batterySource(config, batteryFormatter, display.NewDeviceListFormatter(config), devices)
+	sources := append([]display.SegmentFormatter{source, systemFormatter}, newSparklines(config, devices)...)
+
+	// powerline 模式下所有片段渲染为连续的色块
+	if config.Powerline {
+		fmt.Print(display.NewPowerlineFormatter(config, sources...).Format())
 		return
 	}
 
-	// 输出结果，用空格分隔
-	var outputs []string
-	if batteryOutput != "" {
-		outputs = append(outputs, batteryOutput)
+	var segments []display.Segment
+	for _, source := range sources {
+		segments = append(segments, source.Segments()...)
+	}
+	fmt.Print(display.TmuxSerializer{}.Serialize(segments))
+}
+
+func outputReport(format string) {
+	r := report.New(snapshot.Collect())
+	if err := report.Write(os.Stdout, r, format); err != nil {
+		fmt.Fprintf(os.Stderr, "输出报告失败: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func outputBar(format string, interval time.Duration) {
+	config := tmux.GetConfig()
+	// 主题无效时回退到默认主题
+	_ = display.ApplyTheme(config)
+	batteryFormatter := display.NewBatteryFormatter(config)
+	systemFormatter := display.NewSystemFormatter(config)
+	deviceList := display.NewDeviceListFormatter(config)
+	var bar *display.BarFormatter
+
+	// 获取失败时对应片段为空，状态栏保持运行
+	refresh := func() {
+		devices, devicesErr := battery.GetDevices()
+		batteryInfo, _ := battery.TouchpadFromDevices(devices, devicesErr)
+		saveLevel := restoreLevel(batteryFormatter)
+		batteryFormatter.SetBatteryInfo(batteryInfo)
+		saveLevel()
+		applyETA(batteryFormatter, devices)
+		applyLastSeen(config, batteryFormatter, batteryInfo, devices, devicesErr)
+		systemInfo, _ := system.GetSystemInfo()
+		systemFormatter.SetSystemInfo(systemInfo)
+		bar = display.NewBarFormatter(batterySource(config, batteryFormatter, deviceList, devices), systemFormatter)
 	}
-	if systemOutput != "" {
-		outputs = append(outputs, systemOutput)
+
+	switch format {
+	case "i3bar":
+		writer := display.NewI3barWriter(os.Stdout)
+		for {
+			refresh()
+			if err := writer.Write(bar.I3barBlocks()); err != nil {
+				// 状态栏关闭了管道
+				return
+			}
+			time.Sleep(interval)
+		}
+	case "waybar":
+		refresh()
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(bar.Waybar()); err != nil {
+			os.Exit(1)
+		}
+	case "polybar":
+		refresh()
+		fmt.Println(bar.Polybar())
 	}
+}
 
-	if len(outputs) > 0 {
-		fmt.Print(outputs[0])
-		for _, output := range outputs[1:] {
-			fmt.Print(" ", output)
+func outputPrompt(shell string) {
+	if shell == "" {
+		shell = filepath.Base(os.Getenv("SHELL"))
+		if _, err := display.SerializerFor(shell); err != nil || shell == "tmux" || shell == "polybar" {
+			shell = "plain"
 		}
 	}
+
+	serializer, err := display.SerializerFor(shell)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	config := tmux.GetConfig()
+	// 主题无效时回退到默认主题
+	_ = display.ApplyTheme(config)
+	batteryFormatter := display.NewBatteryFormatter(config)
+	systemFormatter := display.NewSystemFormatter(config)
+
+	// 获取失败时对应片段为空，不影响提示符
+	devices, devicesErr := battery.GetDevices()
+	batteryInfo, _ := battery.TouchpadFromDevices(devices, devicesErr)
+	saveLevel := restoreLevel(batteryFormatter)
+	batteryFormatter.SetBatteryInfo(batteryInfo)
+	saveLevel()
+	applyETA(batteryFormatter, devices)
+	applyLastSeen(config, batteryFormatter, batteryInfo, devices, devicesErr)
+	systemInfo, _ := system.GetSystemInfo()
+	systemFormatter.SetSystemInfo(systemInfo)
+
+	source := batterySource(config, batteryFormatter, display.NewDeviceListFormatter(config), devices)
+	segments := append(source.Segments(), systemFormatter.Segments()...)
+	fmt.Print(serializer.Serialize(segments))
 }
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+	"github.com/akayj/tmux-touchpad-battery/internal/daemon"
+	"github.com/akayj/tmux-touchpad-battery/internal/events"
+	"github.com/akayj/tmux-touchpad-battery/internal/history"
+	"github.com/akayj/tmux-touchpad-battery/internal/metrics"
+	"github.com/akayj/tmux-touchpad-battery/internal/mqtt"
+	"github.com/akayj/tmux-touchpad-battery/internal/notify"
+	"github.com/akayj/tmux-touchpad-battery/internal/presence"
+	"github.com/akayj/tmux-touchpad-battery/internal/tmux"
+	"github.com/akayj/tmux-touchpad-battery/internal/webhook"
+)
+
+// runServe 常驻模式：定时采样并导出指标
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	var (
+		enableMetrics = fs.Bool("metrics", false, "通过 HTTP 导出 Prometheus 指标")
+		listen        = fs.String("listen", "127.0.0.1:9101", "指标 HTTP 监听地址")
+		textfile      = fs.String("textfile", "", "写入 node_exporter textfile collector 的文件路径（.prom）")
+		interval      = fs.Duration("interval", 30*time.Second, "采样间隔")
+		recordHistory = fs.Bool("history", true, "记录电量历史")
+		enableNotify  = fs.Bool("notify", false, "外设电量跌破阈值时发送桌面通知")
+		thresholds    = fs.String("notify-thresholds", "20,10,5", "通知阈值，逗号分隔")
+		hysteresis    = fs.Int("notify-hysteresis", notify.DefaultHysteresis, "电量回升超过阈值多少后重新启用通知")
+		snooze        = fs.Duration("notify-snooze", notify.DefaultSnooze, "同一设备两次通知的最小间隔")
+		mqttAddr      = fs.String("mqtt", "", "发布到 MQTT broker，如 tcp://homeassistant.local:1883")
+		mqttPrefix    = fs.String("mqtt-prefix", mqtt.DefaultPrefix, "MQTT 主题前缀")
+		mqttDiscovery = fs.String("mqtt-discovery-prefix", mqtt.DefaultDiscoveryPrefix, "Home Assistant discovery 主题前缀，为空时不发布")
+		mqttClientID  = fs.String("mqtt-client-id", "", "MQTT 客户端标识（默认根据主机名生成）")
+		mqttUsername  = fs.String("mqtt-username", "", "MQTT 用户名")
+	)
+	_ = fs.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var sinks []daemon.Sink
+
+	if *recordHistory {
+		store, err := history.Open()
+		if err != nil {
+			log.Printf("打开历史记录失败: %v", err)
+		} else {
+			sinks = append(sinks, store)
+		}
+	}
+
+	// 记录设备的连接状态，供状态栏在设备断开时显示最后状态
+	if tracker, err := presence.Open(); err == nil {
+		sinks = append(sinks, tracker)
+	}
+
+	if *enableNotify {
+		alerter, err := newAlerter(*thresholds, *hysteresis, *snooze)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "无法启用通知: %v\n", err)
+			os.Exit(2)
+		}
+		sinks = append(sinks, alerter)
+	}
+
+	// 配置了事件钩子或 webhook 时运行
+	config := tmux.GetConfig()
+	var handlers []events.Handler
+	wh, err := webhook.FromConfig(config)
+	if err != nil {
+		log.Printf("无法启用 webhook: %v", err)
+	} else if wh != nil {
+		handlers = append(handlers, wh)
+	}
+	if path, err := events.StatePath(); err == nil {
+		if watcher := events.NewWatcher(config, path, handlers...); watcher != nil {
+			sinks = append(sinks, watcher)
+		}
+	}
+
+	if *mqttAddr != "" {
+		// 每次采样都会发布消息，心跳间隔取采样间隔的 3 倍
+		publisher := mqtt.NewPublisher(*mqttAddr, mqtt.Options{
+			ClientID:  *mqttClientID,
+			Username:  *mqttUsername,
+			Password:  os.Getenv("TPB_MQTT_PASSWORD"),
+			KeepAlive: max(3**interval, time.Minute),
+		})
+		publisher.Prefix = *mqttPrefix
+		publisher.DiscoveryPrefix = *mqttDiscovery
+		sinks = append(sinks, publisher)
+		defer publisher.Close()
+	}
+
+	if *textfile != "" {
+		sinks = append(sinks, &metrics.TextfileWriter{Path: *textfile})
+	}
+
+	if *enableMetrics {
+		exporter := metrics.NewExporter()
+		sinks = append(sinks, exporter)
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", exporter)
+		server := &http.Server{Addr: *listen, Handler: mux}
+
+		go func() {
+			log.Printf("Prometheus 指标: http://%s/metrics", *listen)
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("HTTP 服务失败: %v", err)
+				stop()
+			}
+		}()
+		defer server.Close()
+	}
+
+	// 提供者支持推送时（如 Linux 上的 UPower）设备变化后立即采样
+	d := daemon.New(*interval, sinks...)
+	if changes, err := battery.WatchDevices(ctx.Done()); err == nil {
+		d.Trigger = changes
+	}
+	_ = d.Run(ctx)
+}
+
+// newAlerter 使用当前平台的通知方式创建低电量提醒
+func newAlerter(thresholds string, hysteresis int, snooze time.Duration) (*notify.Alerter, error) {
+	levels, err := notify.ParseThresholds(thresholds)
+	if err != nil {
+		return nil, err
+	}
+
+	notifier, err := notify.Default()
+	if err != nil {
+		return nil, err
+	}
+
+	alerter := notify.NewAlerter(notifier, levels)
+	alerter.Hysteresis = hysteresis
+	alerter.Snooze = snooze
+	// snooze 子命令写入的暂停时间
+	alerter.SnoozePath, _ = notify.SnoozePath()
+	return alerter, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/notify"
+)
+
+// runSnooze 暂停 tmux 低电量提醒和 serve --notify 的桌面通知
+// 参数为暂停时长（默认 1h），off 表示立即恢复
+func runSnooze(args []string) {
+	fs := flag.NewFlagSet("snooze", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "用法: tmux-touchpad-battery snooze [时长|off]")
+		fmt.Fprintln(os.Stderr, "  时长如 30m、2h（默认: 1h）；off 立即恢复提醒")
+	}
+	_ = fs.Parse(args)
+
+	value := "1h"
+	if fs.NArg() > 0 {
+		value = fs.Arg(0)
+	}
+
+	var until time.Time
+	if value != "off" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			fmt.Fprintf(os.Stderr, "无效的时长: %q\n", value)
+			fs.Usage()
+			os.Exit(2)
+		}
+		until = time.Now().Add(d)
+	}
+
+	path, err := notify.SnoozePath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "无法确定状态目录: %v\n", err)
+		os.Exit(1)
+	}
+	if err := notify.SaveSnooze(path, until); err != nil {
+		fmt.Fprintf(os.Stderr, "保存暂停时间失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	if until.IsZero() {
+		fmt.Println("已恢复低电量提醒")
+		return
+	}
+	fmt.Printf("已暂停低电量提醒至 %s\n", until.Format("2006-01-02 15:04"))
+}
@@ -5,83 +5,125 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // BatteryInfo 表示电池信息
 type BatteryInfo struct {
 	Percentage int
+	// IsCharging 与 State == StateCharging 等价，保留以兼容
 	IsCharging bool
+	State      ChargeState
 	Available  bool
-}
 
-// GetTouchpadBatteryInfo 获取触摸板电池信息
-func GetTouchpadBatteryInfo() (*BatteryInfo, error) {
-	info := &BatteryInfo{}
+	// 设备标识，仅在通过 GetDevices 获取时填充
+	Name    string
+	Serial  string
+	Address string
 
-	// 获取电池百分比
-	percentage, err := getBatteryPercentage()
-	if err != nil {
-		return nil, err
-	}
+	// Kind 提供者报告的设备类别（如 BlueZ 的 Icon），为空时由 Class 根据名称判断
+	Kind string
 
-	if percentage == -1 {
-		info.Available = false
-		return info, nil
-	}
+	// TimeToEmpty 提供者报告的剩余时间（如 UPower），未知时为 0
+	TimeToEmpty time.Duration
+}
 
-	info.Percentage = percentage
-	info.Available = true
+var (
+	productRe = regexp.MustCompile(`"Product"\s*=\s*"([^"]*)"`)
+	serialRe  = regexp.MustCompile(`"SerialNumber"\s*=\s*"([^"]*)"`)
+	addressRe = regexp.MustCompile(`"DeviceAddress"\s*=\s*"([^"]*)"`)
+	percentRe = regexp.MustCompile(`"BatteryPercent"\s*=\s*(\d+)`)
+	flagsRe   = regexp.MustCompile(`"BatteryStatusFlags"\s*=\s*(\d+)`)
+)
+
+// GetDevices 从当前平台的所有提供者获取报告电池电量的外设
+func GetDevices() ([]*BatteryInfo, error) {
+	return Collect(DefaultProviders()...)
+}
+
+// IORegProvider 通过 macOS 的 ioreg 获取外设电量
+type IORegProvider struct{}
 
-	// 获取充电状态
-	isCharging, err := getChargingStatus()
+// Devices 实现 Provider
+func (IORegProvider) Devices() ([]*BatteryInfo, error) {
+	cmd := exec.Command("ioreg", "-r", "-l", "-k", "BatteryPercent")
+	output, err := cmd.Output()
 	if err != nil {
 		return nil, err
 	}
 
-	info.IsCharging = isCharging
-	return info, nil
+	return parseDevices(string(output)), nil
 }
 
-// getBatteryPercentage 获取电池百分比
-func getBatteryPercentage() (int, error) {
-	cmd := exec.Command("ioreg", "-l")
-	output, err := cmd.Output()
-	if err != nil {
-		return -1, err
+// parseDevices 解析 ioreg 输出，每个 "+-o" 节点对应一个设备
+func parseDevices(output string) []*BatteryInfo {
+	var devices []*BatteryInfo
+
+	for _, block := range strings.Split(output, "+-o ")[1:] {
+		matches := percentRe.FindStringSubmatch(block)
+		if len(matches) < 2 {
+			continue
+		}
+
+		percentage, err := strconv.Atoi(matches[1])
+		if err != nil {
+			continue
+		}
+
+		info := &BatteryInfo{
+			Percentage: percentage,
+			Available:  true,
+			Name:       firstSubmatch(productRe, block),
+			Serial:     firstSubmatch(serialRe, block),
+			Address:    firstSubmatch(addressRe, block),
+		}
+		info.State = DecodeStatusFlags(parseFlags(firstSubmatch(flagsRe, block)), percentage)
+		info.IsCharging = info.State == StateCharging
+
+		devices = append(devices, info)
 	}
 
-	// 查找 BatteryPercent
-	re := regexp.MustCompile(`"BatteryPercent"\s*=\s*(\d+)`)
-	matches := re.FindStringSubmatch(string(output))
+	return devices
+}
 
-	if len(matches) < 2 {
-		return -1, nil // 没有找到电池信息
+// parseFlags 解析 BatteryStatusFlags，没有该字段时返回 -1
+func parseFlags(s string) int {
+	flags, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return -1
 	}
+	return flags
+}
 
-	percentage, err := strconv.Atoi(matches[1])
-	if err != nil {
-		return -1, err
+// firstSubmatch 返回正则第一个分组的匹配结果
+func firstSubmatch(re *regexp.Regexp, s string) string {
+	matches := re.FindStringSubmatch(s)
+	if len(matches) < 2 {
+		return ""
 	}
+	return matches[1]
+}
 
-	return percentage, nil
+// GetTouchpadBatteryInfo 获取触摸板电池信息
+// 所有平台都从提供者中选择第一个触摸板，与历史记录和剩余时间估算使用同一个设备
+func GetTouchpadBatteryInfo() (*BatteryInfo, error) {
+	return TouchpadFromDevices(GetDevices())
 }
 
-// getChargingStatus 获取充电状态
-func getChargingStatus() (bool, error) {
-	cmd := exec.Command("ioreg", "-l")
-	output, err := cmd.Output()
+// TouchpadFromDevices 从已获取的设备列表中选择触摸板，避免再次查询提供者
+func TouchpadFromDevices(devices []*BatteryInfo, err error) (*BatteryInfo, error) {
 	if err != nil {
-		return false, err
+		return nil, err
 	}
+	return FindTouchpad(devices), nil
+}
 
-	// 查找 BatteryStatusFlags
-	re := regexp.MustCompile(`"BatteryStatusFlags"\s*=\s*(\d+)`)
-	matches := re.FindStringSubmatch(string(output))
-
-	if len(matches) < 2 {
-		return false, nil
+// FindTouchpad 返回第一个可用的触摸板，没有时返回不可用的信息
+func FindTouchpad(devices []*BatteryInfo) *BatteryInfo {
+	for _, d := range devices {
+		if d.Available && d.Class() == ClassTrackpad {
+			return d
+		}
 	}
-
-	status := strings.TrimSpace(matches[1])
-	return status == "3", nil
+	return &BatteryInfo{}
 }
@@ -28,31 +28,94 @@ func TestGetTouchpadBatteryInfo(t *testing.T) {
 	}
 }
 
-func TestGetBatteryPercentage(t *testing.T) {
-	percentage, err := getBatteryPercentage()
-	if err != nil {
-		t.Logf("获取电池百分比时出错: %v", err)
-		return
+func TestParseDevices(t *testing.T) {
+	output := `+-o AppleDeviceManagementHIDEventService  <class AppleDeviceManagementHIDEventService, id 0x100000b3e>
+    {
+      "BatteryPercent" = 66
+      "Product" = "Magic Trackpad"
+      "SerialNumber" = "CC2000000001"
+      "BatteryStatusFlags" = 3
+      "DeviceAddress" = "f0-00-00-00-00-01"
+    }
++-o AppleDeviceManagementHIDEventService  <class AppleDeviceManagementHIDEventService, id 0x100000c21>
+    {
+      "BatteryPercent" = 12
+      "Product" = "Magic Mouse"
+      "BatteryStatusFlags" = 0
+    }
++-o IOService  <class IOService>
+    {
+      "Product" = "No Battery"
+    }
+`
+
+	devices := parseDevices(output)
+	if len(devices) != 2 {
+		t.Fatalf("应该解析出 2 个设备，实际: %d", len(devices))
 	}
 
-	// -1 表示没有找到电池信息，这是正常的
-	if percentage == -1 {
-		t.Log("没有找到电池信息")
-		return
+	trackpad := devices[0]
+	if trackpad.Name != "Magic Trackpad" || trackpad.Percentage != 66 || !trackpad.IsCharging {
+		t.Errorf("触摸板信息解析错误: %+v", trackpad)
+	}
+	if trackpad.Serial != "CC2000000001" || trackpad.Address != "f0-00-00-00-00-01" {
+		t.Errorf("触摸板标识解析错误: %+v", trackpad)
 	}
 
-	if percentage < 0 || percentage > 100 {
-		t.Errorf("电池百分比应该在 0-100 范围内，实际值: %d", percentage)
+	mouse := devices[1]
+	if mouse.Name != "Magic Mouse" || mouse.Percentage != 12 || mouse.IsCharging || !mouse.Available {
+		t.Errorf("鼠标信息解析错误: %+v", mouse)
+	}
+	if trackpad.State != StateCharging || mouse.State != StateDischarging {
+		t.Errorf("充电状态解析错误: %v, %v", trackpad.State, mouse.State)
 	}
 }
 
-func TestGetChargingStatus(t *testing.T) {
-	isCharging, err := getChargingStatus()
-	if err != nil {
-		t.Logf("获取充电状态时出错: %v", err)
-		return
+func TestDecodeStatusFlags(t *testing.T) {
+	tests := []struct {
+		flags, percentage int
+		want              ChargeState
+	}{
+		{-1, 50, StateUnknown},
+		{0, 50, StateDischarging},
+		{3, 50, StateCharging},
+		{3, 100, StateCharging},
+		{1, 100, StateFull},
+		{1, 80, StateNotCharging},
+	}
+	for _, tt := range tests {
+		if got := DecodeStatusFlags(tt.flags, tt.percentage); got != tt.want {
+			t.Errorf("DecodeStatusFlags(%d, %d) = %v, want %v", tt.flags, tt.percentage, got, tt.want)
+		}
 	}
+}
 
-	// 充电状态应该是布尔值，这里只是记录结果
-	t.Logf("充电状态: %v", isCharging)
+func TestParseStatus(t *testing.T) {
+	tests := map[string]ChargeState{
+		"Charging":      StateCharging,
+		"Discharging\n": StateDischarging,
+		"Full":          StateFull,
+		"Not charging":  StateNotCharging,
+		"not charging":  StateNotCharging,
+		"Unknown":       StateUnknown,
+		"":              StateUnknown,
+	}
+	for in, want := range tests {
+		if got := ParseStatus(in); got != want {
+			t.Errorf("ParseStatus(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestFindTouchpad(t *testing.T) {
+	mouse := &BatteryInfo{Name: "MX Master 3", Kind: ClassMouse, Available: true}
+	old := &BatteryInfo{Name: "Magic Trackpad", Available: false}
+	trackpad := &BatteryInfo{Name: "Tablet", Kind: ClassTrackpad, Percentage: 40, Available: true}
+
+	if got := FindTouchpad([]*BatteryInfo{mouse, old, trackpad}); got != trackpad {
+		t.Errorf("应该选择第一个可用的触摸板, 实际 %+v", got)
+	}
+	if got := FindTouchpad([]*BatteryInfo{mouse}); got.Available {
+		t.Errorf("没有触摸板时应该不可用, 实际 %+v", got)
+	}
 }
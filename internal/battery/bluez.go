@@ -0,0 +1,81 @@
+package battery
+
+import (
+	"fmt"
+	"sort"
+)
+
+// BlueZ 的 D-Bus 名称
+const (
+	bluezService = "org.bluez"
+	bluezDevice  = "org.bluez.Device1"
+	bluezBattery = "org.bluez.Battery1"
+)
+
+// bluezIconKinds BlueZ 的 Icon 属性到设备类别的映射
+var bluezIconKinds = map[string]string{
+	"input-mouse":    ClassMouse,
+	"input-keyboard": ClassKeyboard,
+	"input-tablet":   ClassTrackpad,
+}
+
+// BlueZProvider 通过 BlueZ 的 org.bluez.Battery1 接口获取蓝牙外设的电量
+// 许多蓝牙鼠标和键盘只通过该接口报告电量，不出现在 sysfs 中
+type BlueZProvider struct {
+	Bus Bus
+}
+
+// NewBlueZProvider 创建使用系统总线的 BlueZ 提供者
+func NewBlueZProvider() *BlueZProvider {
+	return &BlueZProvider{Bus: ExecBus{}}
+}
+
+// Devices 实现 Provider，按对象路径排序返回带 Battery1 接口的设备
+// BlueZ 不报告充电状态，State 为 StateUnknown
+func (p *BlueZProvider) Devices() ([]*BatteryInfo, error) {
+	objects, err := p.Bus.ManagedObjects(bluezService)
+	if err != nil {
+		return nil, fmt.Errorf("查询 BlueZ 失败: %w", err)
+	}
+
+	paths := make([]string, 0, len(objects))
+	for path := range objects {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var devices []*BatteryInfo
+	for _, path := range paths {
+		bat, ok := objects[path][bluezBattery]
+		if !ok {
+			continue
+		}
+		percentage, ok := bat["Percentage"].(float64)
+		if !ok {
+			continue
+		}
+
+		dev := objects[path][bluezDevice]
+		name, _ := dev["Alias"].(string)
+		if name == "" {
+			name, _ = dev["Name"].(string)
+		}
+		address, _ := dev["Address"].(string)
+		icon, _ := dev["Icon"].(string)
+
+		// 没有 Connected 属性时按已连接处理
+		connected, ok := dev["Connected"].(bool)
+		if !ok {
+			connected = true
+		}
+
+		devices = append(devices, &BatteryInfo{
+			Percentage: int(percentage),
+			Available:  connected,
+			Name:       name,
+			Address:    address,
+			Kind:       bluezIconKinds[icon],
+		})
+	}
+	return devices, nil
+}
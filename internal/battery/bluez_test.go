@@ -0,0 +1,91 @@
+package battery
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBlueZProvider(t *testing.T) {
+	bus := &fakeBus{objects: ManagedObjects{
+		"/org/bluez/hci0": {
+			"org.bluez.Adapter1": {"Address": "00:11:22:33:44:55"},
+		},
+		"/org/bluez/hci0/dev_AA_BB_CC_DD_EE_02": {
+			"org.bluez.Device1":  {"Alias": "MX Keys", "Address": "AA:BB:CC:DD:EE:02", "Icon": "input-keyboard", "Connected": true},
+			"org.bluez.Battery1": {"Percentage": float64(64)},
+		},
+		"/org/bluez/hci0/dev_AA_BB_CC_DD_EE_01": {
+			"org.bluez.Device1":  {"Name": "MX Master 3", "Address": "AA:BB:CC:DD:EE:01", "Icon": "input-mouse"},
+			"org.bluez.Battery1": {"Percentage": float64(85)},
+		},
+		"/org/bluez/hci0/dev_AA_BB_CC_DD_EE_03": {
+			"org.bluez.Device1": {"Alias": "Speaker", "Icon": "audio-card", "Connected": true},
+		},
+		"/org/bluez/hci0/dev_AA_BB_CC_DD_EE_04": {
+			"org.bluez.Device1":  {"Alias": "Old Tablet", "Icon": "input-tablet", "Connected": false},
+			"org.bluez.Battery1": {"Percentage": float64(10)},
+		},
+	}}
+
+	devices, err := (&BlueZProvider{Bus: bus}).Devices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bus.service != "org.bluez" {
+		t.Errorf("查询的服务 = %q", bus.service)
+	}
+	if len(devices) != 3 {
+		t.Fatalf("应该有 3 个带电池的设备，实际 %d 个", len(devices))
+	}
+
+	// 按对象路径排序；没有 Alias 时使用 Name
+	mouse, keyboard, tablet := devices[0], devices[1], devices[2]
+	if mouse.Name != "MX Master 3" || mouse.Percentage != 85 || mouse.Address != "AA:BB:CC:DD:EE:01" ||
+		mouse.Class() != ClassMouse || !mouse.Available {
+		t.Errorf("鼠标信息错误: %+v", mouse)
+	}
+	if keyboard.Name != "MX Keys" || keyboard.Percentage != 64 || keyboard.Class() != ClassKeyboard {
+		t.Errorf("键盘信息错误: %+v", keyboard)
+	}
+	if tablet.Available || tablet.Class() != ClassTrackpad {
+		t.Errorf("未连接的设备信息错误: %+v", tablet)
+	}
+	if mouse.State != StateUnknown {
+		t.Errorf("BlueZ 不报告充电状态, State = %v", mouse.State)
+	}
+}
+
+func TestBlueZProviderError(t *testing.T) {
+	bus := &fakeBus{err: errors.New("no bus")}
+	if _, err := (&BlueZProvider{Bus: bus}).Devices(); err == nil {
+		t.Error("总线错误时应该返回错误")
+	}
+}
+
+// staticProvider 返回固定结果的提供者
+type staticProvider struct {
+	devices []*BatteryInfo
+	err     error
+}
+
+func (p staticProvider) Devices() ([]*BatteryInfo, error) {
+	return p.devices, p.err
+}
+
+func TestCollect(t *testing.T) {
+	mouse := &BatteryInfo{Name: "Mouse", Available: true}
+	keyboard := &BatteryInfo{Name: "Keyboard", Available: true}
+	failing := staticProvider{err: errors.New("unavailable")}
+
+	devices, err := Collect(staticProvider{devices: []*BatteryInfo{mouse}}, failing, staticProvider{devices: []*BatteryInfo{keyboard}})
+	if err != nil {
+		t.Fatalf("部分提供者失败时不应返回错误: %v", err)
+	}
+	if len(devices) != 2 || devices[0] != mouse || devices[1] != keyboard {
+		t.Errorf("合并结果错误: %v", devices)
+	}
+
+	if _, err := Collect(failing, failing); err == nil {
+		t.Error("所有提供者都失败时应该返回错误")
+	}
+}
@@ -0,0 +1,42 @@
+package battery
+
+import "strings"
+
+// 设备类别，用于按类别覆盖显示配置
+const (
+	ClassTrackpad = "trackpad"
+	ClassMouse    = "mouse"
+	ClassKeyboard = "keyboard"
+)
+
+// Classes 所有已知的设备类别
+var Classes = []string{ClassTrackpad, ClassMouse, ClassKeyboard}
+
+// classKeywords 设备名称中的关键字到类别的映射
+var classKeywords = []struct {
+	keyword, class string
+}{
+	{"trackpad", ClassTrackpad},
+	{"touchpad", ClassTrackpad},
+	{"mouse", ClassMouse},
+	{"keyboard", ClassKeyboard},
+}
+
+// Class 返回设备类别，提供者没有报告时根据设备名称判断，无法判断时返回空字符串
+// 没有名称的设备（如 ioreg 中没有 Product）视为触摸板
+func (b *BatteryInfo) Class() string {
+	if b.Kind != "" {
+		return b.Kind
+	}
+	if b.Name == "" {
+		return ClassTrackpad
+	}
+
+	name := strings.ToLower(b.Name)
+	for _, k := range classKeywords {
+		if strings.Contains(name, k.keyword) {
+			return k.class
+		}
+	}
+	return ""
+}
@@ -0,0 +1,230 @@
+package battery
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// D-Bus 标准接口
+const (
+	objectManager     = "org.freedesktop.DBus.ObjectManager"
+	propertiesIface   = "org.freedesktop.DBus.Properties"
+	propertiesChanged = "PropertiesChanged"
+)
+
+// ManagedObjects ObjectManager.GetManagedObjects 的结果：对象路径 → 接口 → 属性
+// 属性值为解开 variant 后的值，数字统一为 float64
+type ManagedObjects map[string]map[string]map[string]any
+
+// Signal 服务发出的 D-Bus 信号
+// PropertiesChanged 信号的 Interface 为属性所属的接口，而不是 org.freedesktop.DBus.Properties
+type Signal struct {
+	Path      string
+	Interface string
+	Member    string
+}
+
+// Bus D-Bus 总线，测试时可以替换
+type Bus interface {
+	// ManagedObjects 调用服务根对象的 GetManagedObjects
+	ManagedObjects(service string) (ManagedObjects, error)
+
+	// ObjectPaths 调用返回对象路径数组（ao）的无参数方法，如 UPower 的 EnumerateDevices
+	ObjectPaths(service, path, iface, method string) ([]string, error)
+
+	// Properties 返回对象上一个接口的所有属性（Properties.GetAll），已解开 variant
+	Properties(service, path, iface string) (map[string]any, error)
+
+	// Subscribe 订阅服务发出的信号，stop 关闭后停止并关闭返回的通道
+	Subscribe(service string, stop <-chan struct{}) (<-chan Signal, error)
+}
+
+// ExecBus 通过命令行工具访问 D-Bus，Address 为空时使用系统总线
+// 方法调用使用 busctl 的 JSON 输出；订阅信号使用 gdbus monitor，
+// 它通过匹配规则接收信号，不像 busctl monitor 那样需要 root 权限
+type ExecBus struct {
+	Address string
+}
+
+// busArgs 返回 busctl 选择总线和输出格式的参数
+func (b ExecBus) busArgs() []string {
+	if b.Address != "" {
+		return []string{"--json=short", "--address=" + b.Address}
+	}
+	return []string{"--json=short", "--system"}
+}
+
+// call 调用方法，返回 busctl 输出的返回值
+func (b ExecBus) call(args ...string) (*busctlMessage, error) {
+	output, err := exec.Command("busctl", append(append(b.busArgs(), "call"), args...)...).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var reply busctlMessage
+	if err := json.Unmarshal(output, &reply); err != nil {
+		return nil, fmt.Errorf("解析 busctl 输出失败: %w", err)
+	}
+	return &reply, nil
+}
+
+// ManagedObjects 实现 Bus
+func (b ExecBus) ManagedObjects(service string) (ManagedObjects, error) {
+	output, err := exec.Command("busctl", append(append(b.busArgs(), "call"),
+		service, "/", objectManager, "GetManagedObjects")...).Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseBusctlObjects(output)
+}
+
+// ObjectPaths 实现 Bus
+func (b ExecBus) ObjectPaths(service, path, iface, method string) ([]string, error) {
+	reply, err := b.call(service, path, iface, method)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths [][]string
+	if err := json.Unmarshal(reply.Data, &paths); err != nil || len(paths) != 1 {
+		return nil, fmt.Errorf("%s 返回了无效的结果: %s", method, reply.Data)
+	}
+	return paths[0], nil
+}
+
+// Properties 实现 Bus
+func (b ExecBus) Properties(service, path, iface string) (map[string]any, error) {
+	reply, err := b.call(service, path, propertiesIface, "GetAll", "s", iface)
+	if err != nil {
+		return nil, err
+	}
+
+	var props []map[string]busctlVariant
+	if err := json.Unmarshal(reply.Data, &props); err != nil || len(props) != 1 {
+		return nil, fmt.Errorf("GetAll 返回了无效的结果: %s", reply.Data)
+	}
+	return unwrapVariants(props[0]), nil
+}
+
+// Subscribe 实现 Bus，通过 gdbus monitor 接收服务发出的信号
+func (b ExecBus) Subscribe(service string, stop <-chan struct{}) (<-chan Signal, error) {
+	bus := []string{"--system"}
+	if b.Address != "" {
+		bus = []string{"--address", b.Address}
+	}
+	cmd := exec.Command("gdbus", append(append([]string{"monitor"}, bus...), "--dest", service)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	// stop 关闭后结束 gdbus monitor；gdbus 自行退出时不再等待 stop
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-stop:
+			_ = cmd.Process.Kill()
+		case <-done:
+		}
+	}()
+
+	signals := make(chan Signal)
+	go func() {
+		defer close(signals)
+		defer close(done)
+		defer cmd.Wait()
+
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			sig, ok := parseMonitorLine(scanner.Text())
+			if !ok {
+				continue
+			}
+			select {
+			case signals <- sig:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return signals, nil
+}
+
+// busctlMessage busctl JSON 输出中的方法返回值
+type busctlMessage struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// busctlVariant busctl JSON 输出中的 variant
+type busctlVariant struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// unwrapVariants 解开属性表中的 variant
+func unwrapVariants(props map[string]busctlVariant) map[string]any {
+	values := make(map[string]any, len(props))
+	for name, v := range props {
+		values[name] = v.Data
+	}
+	return values
+}
+
+// parseBusctlObjects 解析 busctl --json 输出的 GetManagedObjects 结果（类型 a{oa{sa{sv}}}）
+func parseBusctlObjects(output []byte) (ManagedObjects, error) {
+	var reply struct {
+		Data []map[string]map[string]map[string]busctlVariant `json:"data"`
+	}
+	if err := json.Unmarshal(output, &reply); err != nil {
+		return nil, fmt.Errorf("解析 busctl 输出失败: %w", err)
+	}
+	if len(reply.Data) != 1 {
+		return nil, fmt.Errorf("GetManagedObjects 返回了 %d 个值", len(reply.Data))
+	}
+
+	objects := make(ManagedObjects, len(reply.Data[0]))
+	for path, ifaces := range reply.Data[0] {
+		objects[path] = make(map[string]map[string]any, len(ifaces))
+		for iface, props := range ifaces {
+			objects[path][iface] = unwrapVariants(props)
+		}
+	}
+	return objects, nil
+}
+
+// parseMonitorLine 解析 gdbus monitor 输出的一行信号，不是信号时 ok 为 false
+// 格式为 "<path>: <interface>.<member> (<参数>)"，只解析 PropertiesChanged 的第一个参数（属性所属的接口）
+func parseMonitorLine(line string) (sig Signal, ok bool) {
+	objectPath, rest, ok := strings.Cut(line, ": ")
+	if !ok || !strings.HasPrefix(objectPath, "/") {
+		return Signal{}, false
+	}
+	name, args, _ := strings.Cut(rest, " ")
+	dot := strings.LastIndex(name, ".")
+	if dot <= 0 {
+		return Signal{}, false
+	}
+
+	sig = Signal{Path: objectPath, Interface: name[:dot], Member: name[dot+1:]}
+	if sig.Interface == propertiesIface && sig.Member == propertiesChanged {
+		// 参数形如 ('org.freedesktop.UPower.Device', {...}, @as [])
+		_, quoted, ok := strings.Cut(args, "'")
+		if !ok {
+			return Signal{}, false
+		}
+		iface, _, ok := strings.Cut(quoted, "'")
+		if !ok {
+			return Signal{}, false
+		}
+		sig.Interface = iface
+	}
+	return sig, true
+}
@@ -0,0 +1,90 @@
+package battery
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakeBus 内存中的 D-Bus 总线，记录最后查询的服务
+type fakeBus struct {
+	objects    ManagedObjects
+	paths      []string
+	properties map[string]map[string]any
+	signals    chan Signal
+	err        error
+	service    string
+}
+
+func (b *fakeBus) ManagedObjects(service string) (ManagedObjects, error) {
+	b.service = service
+	return b.objects, b.err
+}
+
+func (b *fakeBus) ObjectPaths(service, path, iface, method string) ([]string, error) {
+	b.service = service
+	return b.paths, b.err
+}
+
+func (b *fakeBus) Properties(service, path, iface string) (map[string]any, error) {
+	props, ok := b.properties[path]
+	if !ok {
+		return nil, errors.New("no such object")
+	}
+	return props, nil
+}
+
+func (b *fakeBus) Subscribe(service string, stop <-chan struct{}) (<-chan Signal, error) {
+	b.service = service
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.signals, nil
+}
+
+func TestParseBusctlObjects(t *testing.T) {
+	output := `{"type":"a{oa{sa{sv}}}","data":[{"/org/bluez/hci0/dev_AA_BB_CC_DD_EE_01":{"org.bluez.Device1":{"Alias":{"type":"s","data":"MX Master 3"},"Connected":{"type":"b","data":true}},"org.bluez.Battery1":{"Percentage":{"type":"y","data":85}}}}]}`
+
+	objects, err := parseBusctlObjects([]byte(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ifaces := objects["/org/bluez/hci0/dev_AA_BB_CC_DD_EE_01"]
+	if ifaces["org.bluez.Device1"]["Alias"] != "MX Master 3" || ifaces["org.bluez.Device1"]["Connected"] != true {
+		t.Errorf("Device1 属性解析错误: %v", ifaces["org.bluez.Device1"])
+	}
+	if ifaces["org.bluez.Battery1"]["Percentage"] != float64(85) {
+		t.Errorf("Battery1 属性解析错误: %v", ifaces["org.bluez.Battery1"])
+	}
+
+	if _, err := parseBusctlObjects([]byte("not json")); err == nil {
+		t.Error("无效输出应该返回错误")
+	}
+}
+
+func TestParseMonitorLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want Signal
+		ok   bool
+	}{
+		{
+			"/org/freedesktop/UPower/devices/mouse_1: org.freedesktop.DBus.Properties.PropertiesChanged ('org.freedesktop.UPower.Device', {'Percentage': <42.5>, 'State': <uint32 2>}, @as [])",
+			Signal{Path: "/org/freedesktop/UPower/devices/mouse_1", Interface: "org.freedesktop.UPower.Device", Member: "PropertiesChanged"},
+			true,
+		},
+		{
+			"/org/freedesktop/UPower: org.freedesktop.UPower.DeviceAdded (objectpath '/org/freedesktop/UPower/devices/mouse_2',)",
+			Signal{Path: "/org/freedesktop/UPower", Interface: "org.freedesktop.UPower", Member: "DeviceAdded"},
+			true,
+		},
+		{"Monitoring signals from all objects owned by org.freedesktop.UPower", Signal{}, false},
+		{"The name org.freedesktop.UPower is owned by :1.7", Signal{}, false},
+	}
+
+	for _, tt := range tests {
+		sig, ok := parseMonitorLine(tt.line)
+		if ok != tt.ok || sig != tt.want {
+			t.Errorf("parseMonitorLine(%q) = %+v, %v, want %+v, %v", tt.line, sig, ok, tt.want, tt.ok)
+		}
+	}
+}
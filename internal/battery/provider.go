@@ -0,0 +1,118 @@
+package battery
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Provider 外设电量的来源
+type Provider interface {
+	// Devices 返回所有报告电池电量的外设
+	Devices() ([]*BatteryInfo, error)
+}
+
+// Watcher 可以推送设备变化的提供者
+type Watcher interface {
+	// Watch 在设备变化时向返回的通道发送通知，stop 关闭或订阅中断后关闭通道
+	Watch(stop <-chan struct{}) (<-chan struct{}, error)
+}
+
+// DefaultProviders 返回当前平台的提供者
+// macOS 使用 ioreg；Linux 优先使用 UPower（报告充电状态和剩余时间），UPower 不可用时直接读取 sysfs，
+// 再通过 BlueZ 补充 UPower 没有报告的蓝牙外设；BSD 没有 sysfs
+func DefaultProviders() []Provider {
+	switch runtime.GOOS {
+	case "linux":
+		return []Provider{FallbackProvider{Primary: NewUPowerProvider(), Fallback: NewSysfsProvider()}, NewBlueZProvider()}
+	case "freebsd", "openbsd", "netbsd":
+		return []Provider{NewUPowerProvider(), NewBlueZProvider()}
+	}
+	return []Provider{IORegProvider{}}
+}
+
+// Collect 按顺序合并多个提供者的设备，只有全部失败时才返回错误
+// 地址相同的设备只保留先出现的一个
+func Collect(providers ...Provider) ([]*BatteryInfo, error) {
+	var devices []*BatteryInfo
+	var errs []error
+	seen := make(map[string]bool)
+	for _, p := range providers {
+		found, err := p.Devices()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, d := range found {
+			if d.Address != "" {
+				addr := normalizeAddress(d.Address)
+				if seen[addr] {
+					continue
+				}
+				seen[addr] = true
+			}
+			devices = append(devices, d)
+		}
+	}
+
+	if len(errs) > 0 && len(errs) == len(providers) {
+		return nil, errors.Join(errs...)
+	}
+	return devices, nil
+}
+
+// WatchDevices 合并当前平台所有支持推送的提供者的设备变化通知
+// 没有提供者支持推送或全部订阅失败时返回错误，调用方应回退到定时采样
+func WatchDevices(stop <-chan struct{}) (<-chan struct{}, error) {
+	return Watch(stop, DefaultProviders()...)
+}
+
+// Watch 合并多个提供者的设备变化通知，所有订阅都结束后关闭通道
+func Watch(stop <-chan struct{}, providers ...Provider) (<-chan struct{}, error) {
+	var sources []<-chan struct{}
+	var errs []error
+	for _, p := range providers {
+		w, ok := p.(Watcher)
+		if !ok {
+			continue
+		}
+		ch, err := w.Watch(stop)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sources = append(sources, ch)
+	}
+	if len(sources) == 0 {
+		if len(errs) == 0 {
+			return nil, errors.New("没有支持推送的设备提供者")
+		}
+		return nil, errors.Join(errs...)
+	}
+
+	changes := make(chan struct{}, 1)
+	var wg sync.WaitGroup
+	for _, ch := range sources {
+		wg.Add(1)
+		go func(ch <-chan struct{}) {
+			defer wg.Done()
+			for range ch {
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}(ch)
+	}
+	go func() {
+		wg.Wait()
+		close(changes)
+	}()
+	return changes, nil
+}
+
+// normalizeAddress 统一设备地址的格式，ioreg 使用 "-" 分隔，BlueZ 和 UPower 使用 ":"
+func normalizeAddress(addr string) string {
+	return strings.ToUpper(strings.ReplaceAll(addr, "-", ":"))
+}
@@ -0,0 +1,87 @@
+package battery
+
+import (
+	"strings"
+)
+
+// ChargeState 电池的充电状态
+type ChargeState int
+
+const (
+	StateUnknown ChargeState = iota
+	StateDischarging
+	StateCharging
+	// StateFull 已充满并接通电源（涓流充电）
+	StateFull
+	// StateNotCharging 接通电源但没有充电
+	StateNotCharging
+)
+
+// String 返回状态名称，用于报告和消息
+func (s ChargeState) String() string {
+	switch s {
+	case StateDischarging:
+		return "discharging"
+	case StateCharging:
+		return "charging"
+	case StateFull:
+		return "full"
+	case StateNotCharging:
+		return "not_charging"
+	}
+	return "unknown"
+}
+
+// PluggedIn 判断是否接通了电源
+func (s ChargeState) PluggedIn() bool {
+	return s == StateCharging || s == StateFull || s == StateNotCharging
+}
+
+// BatteryStatusFlags 中的位
+const (
+	// flagExternalPower 接通外部电源
+	flagExternalPower = 1 << 0
+	// flagCharging 正在充电
+	flagCharging = 1 << 1
+)
+
+// DecodeStatusFlags 解码 ioreg 的 BatteryStatusFlags
+// 已知取值：0 未接电源，3 接通电源并充电，1 接通电源但没有充电（电量已满时为涓流充电）
+func DecodeStatusFlags(flags, percentage int) ChargeState {
+	switch {
+	case flags < 0:
+		return StateUnknown
+	case flags&flagCharging != 0:
+		return StateCharging
+	case flags&flagExternalPower != 0:
+		if percentage >= 100 {
+			return StateFull
+		}
+		return StateNotCharging
+	}
+	return StateDischarging
+}
+
+// ParseStatus 解析 Linux power_supply 的状态字符串
+// 如 /sys/class/power_supply/*/status 中的 "Charging"、"Discharging"、"Full"、"Not charging"
+func ParseStatus(s string) ChargeState {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "charging":
+		return StateCharging
+	case "discharging":
+		return StateDischarging
+	case "full":
+		return StateFull
+	case "not charging":
+		return StateNotCharging
+	}
+	return StateUnknown
+}
+
+// ChargeState 返回设备的充电状态，只设置了 IsCharging 时按 IsCharging 推断
+func (b *BatteryInfo) ChargeState() ChargeState {
+	if b.State == StateUnknown && b.IsCharging {
+		return StateCharging
+	}
+	return b.State
+}
@@ -0,0 +1,132 @@
+package battery
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// sysfsRoot Linux 内核导出电源信息的目录
+const sysfsRoot = "/sys/class/power_supply"
+
+// sysfsLevels 只报告粗略电量的设备（capacity_level）对应的百分比，与 upowerLevels 一致
+var sysfsLevels = map[string]int{
+	"critical": 1,
+	"low":      10,
+	"normal":   55,
+	"high":     80,
+	"full":     100,
+}
+
+// SysfsProvider 直接读取 /sys/class/power_supply 中外设（scope 为 Device）的电量
+// UPower 不可用时作为后备，状态取自 status 文件
+type SysfsProvider struct {
+	Root string
+}
+
+// NewSysfsProvider 创建读取 /sys/class/power_supply 的提供者
+func NewSysfsProvider() *SysfsProvider {
+	return &SysfsProvider{Root: sysfsRoot}
+}
+
+// Devices 实现 Provider，按目录名排序返回外设，笔记本电池和电源适配器被忽略
+func (p *SysfsProvider) Devices() ([]*BatteryInfo, error) {
+	entries, err := os.ReadDir(p.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []*BatteryInfo
+	for _, entry := range entries {
+		if info, ok := p.device(entry.Name()); ok {
+			devices = append(devices, info)
+		}
+	}
+	return devices, nil
+}
+
+// device 读取单个 power_supply 目录，不是外设电池时返回 false
+func (p *SysfsProvider) device(name string) (*BatteryInfo, bool) {
+	dir := filepath.Join(p.Root, name)
+	read := func(file string) string {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(data))
+	}
+
+	if read("type") != "Battery" || read("scope") != "Device" {
+		return nil, false
+	}
+	if present := read("present"); present == "0" {
+		return nil, false
+	}
+
+	percentage, err := strconv.Atoi(read("capacity"))
+	if err != nil {
+		level, ok := sysfsLevels[strings.ToLower(read("capacity_level"))]
+		if !ok {
+			return nil, false
+		}
+		percentage = level
+	}
+
+	info := &BatteryInfo{
+		Percentage: percentage,
+		State:      ParseStatus(read("status")),
+		Available:  true,
+		Name:       read("model_name"),
+		Serial:     read("serial_number"),
+		Address:    hidBatteryAddress(name),
+	}
+	if info.Name == "" {
+		info.Name = name
+	}
+	info.IsCharging = info.State == StateCharging
+	return info, true
+}
+
+// hidBatteryAddress 从 hid-<地址>-battery 形式的名称中取出蓝牙地址，不是蓝牙设备时返回空
+func hidBatteryAddress(name string) string {
+	addr, ok := strings.CutPrefix(name, "hid-")
+	if !ok {
+		return ""
+	}
+	addr, ok = strings.CutSuffix(addr, "-battery")
+	if !ok || len(addr) != 17 || strings.Count(addr, ":") != 5 {
+		return ""
+	}
+	return addr
+}
+
+// FallbackProvider Primary 失败时使用 Fallback 的结果
+// 设备变化的推送只来自 Primary
+type FallbackProvider struct {
+	Primary  Provider
+	Fallback Provider
+}
+
+// Devices 实现 Provider
+func (p FallbackProvider) Devices() ([]*BatteryInfo, error) {
+	devices, err := p.Primary.Devices()
+	if err == nil {
+		return devices, nil
+	}
+	fallback, fallbackErr := p.Fallback.Devices()
+	if fallbackErr != nil {
+		return nil, errors.Join(err, fallbackErr)
+	}
+	return fallback, nil
+}
+
+// Watch 实现 Watcher
+func (p FallbackProvider) Watch(stop <-chan struct{}) (<-chan struct{}, error) {
+	w, ok := p.Primary.(Watcher)
+	if !ok {
+		return nil, errors.New("设备提供者不支持推送")
+	}
+	return w.Watch(stop)
+}
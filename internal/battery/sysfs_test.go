@@ -0,0 +1,94 @@
+package battery
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeSupply 在 root 下创建一个 power_supply 目录
+func writeSupply(t *testing.T, root, name string, files map[string]string) {
+	t.Helper()
+	dir := filepath.Join(root, name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSysfsProvider(t *testing.T) {
+	root := t.TempDir()
+	writeSupply(t, root, "AC", map[string]string{"type": "Mains", "online": "1"})
+	writeSupply(t, root, "BAT0", map[string]string{"type": "Battery", "scope": "System", "capacity": "90", "status": "Charging"})
+	writeSupply(t, root, "hid-aa:bb:cc:dd:ee:01-battery", map[string]string{
+		"type": "Battery", "scope": "Device", "model_name": "Magic Trackpad",
+		"capacity": "42", "status": "Discharging",
+	})
+	writeSupply(t, root, "hidpp_battery_1", map[string]string{
+		"type": "Battery", "scope": "Device", "model_name": "K380", "serial_number": "1234-5678",
+		"capacity_level": "Low", "status": "Not charging",
+	})
+	writeSupply(t, root, "hidpp_battery_2", map[string]string{
+		"type": "Battery", "scope": "Device", "present": "0", "capacity": "50",
+	})
+	writeSupply(t, root, "wacom_battery_0", map[string]string{
+		"type": "Battery", "scope": "Device", "capacity": "100", "status": "Full",
+	})
+
+	devices, err := (&SysfsProvider{Root: root}).Devices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 电源适配器、笔记本电池和不在位的设备被忽略
+	if len(devices) != 3 {
+		t.Fatalf("应该有 3 个设备，实际 %d 个", len(devices))
+	}
+
+	trackpad, keyboard, tablet := devices[0], devices[1], devices[2]
+	if trackpad.Name != "Magic Trackpad" || trackpad.Percentage != 42 || trackpad.State != StateDischarging ||
+		trackpad.Address != "aa:bb:cc:dd:ee:01" || trackpad.Class() != ClassTrackpad || !trackpad.Available {
+		t.Errorf("触摸板信息错误: %+v", trackpad)
+	}
+	// 只报告粗略电量的设备使用与 UPower 相同的近似百分比
+	if keyboard.Percentage != 10 || keyboard.State != StateNotCharging || keyboard.Serial != "1234-5678" || keyboard.Address != "" {
+		t.Errorf("键盘信息错误: %+v", keyboard)
+	}
+	// 没有型号时使用目录名
+	if tablet.Name != "wacom_battery_0" || tablet.State != StateFull {
+		t.Errorf("数位板信息错误: %+v", tablet)
+	}
+}
+
+func TestSysfsProviderMissingRoot(t *testing.T) {
+	if _, err := (&SysfsProvider{Root: filepath.Join(t.TempDir(), "missing")}).Devices(); err == nil {
+		t.Error("目录不存在时应该返回错误")
+	}
+}
+
+func TestFallbackProvider(t *testing.T) {
+	mouse := &BatteryInfo{Name: "Mouse", Available: true}
+	keyboard := &BatteryInfo{Name: "Keyboard", Available: true}
+	failing := staticProvider{err: errors.New("unavailable")}
+
+	devices, err := FallbackProvider{Primary: staticProvider{devices: []*BatteryInfo{mouse}}, Fallback: staticProvider{devices: []*BatteryInfo{keyboard}}}.Devices()
+	if err != nil || len(devices) != 1 || devices[0] != mouse {
+		t.Errorf("主提供者成功时应该使用它的结果: %v, %v", devices, err)
+	}
+
+	devices, err = FallbackProvider{Primary: failing, Fallback: staticProvider{devices: []*BatteryInfo{keyboard}}}.Devices()
+	if err != nil || len(devices) != 1 || devices[0] != keyboard {
+		t.Errorf("主提供者失败时应该使用后备的结果: %v, %v", devices, err)
+	}
+
+	if _, err := (FallbackProvider{Primary: failing, Fallback: failing}).Devices(); err == nil {
+		t.Error("都失败时应该返回错误")
+	}
+	if _, err := (FallbackProvider{Primary: failing, Fallback: failing}).Watch(nil); err == nil {
+		t.Error("主提供者不支持推送时应该返回错误")
+	}
+}
@@ -0,0 +1,158 @@
+package battery
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// UPower 的 D-Bus 名称
+const (
+	upowerService = "org.freedesktop.UPower"
+	upowerPath    = "/org/freedesktop/UPower"
+	upowerDevice  = "org.freedesktop.UPower.Device"
+)
+
+// upowerKinds UPower 的设备类型（Type 属性）到设备类别的映射，只列出需要的类型
+// 笔记本电池和 UPS 没有对应的类别，按名称判断
+var upowerKinds = map[uint32]string{
+	2:  "", // battery
+	3:  "", // ups
+	5:  ClassMouse,
+	6:  ClassKeyboard,
+	14: ClassTrackpad,
+}
+
+// upowerStates UPower 的 State 属性到充电状态的映射
+var upowerStates = map[uint32]ChargeState{
+	1: StateCharging,
+	2: StateDischarging,
+	3: StateDischarging, // empty
+	4: StateFull,
+	5: StateNotCharging, // pending charge
+	6: StateDischarging, // pending discharge
+}
+
+// upowerLevels 只报告粗略电量的设备（BatteryLevel 属性）对应的百分比，与 UPower 的近似值一致
+// 0 未知、1 没有粗略电量（使用 Percentage）不在表中
+var upowerLevels = map[uint32]int{
+	3: 10,  // low
+	4: 1,   // critical
+	6: 55,  // normal
+	7: 80,  // high
+	8: 100, // full
+}
+
+// UPowerProvider 通过 org.freedesktop.UPower 获取鼠标、键盘、触摸板、电池和 UPS 的电量
+type UPowerProvider struct {
+	Bus Bus
+}
+
+// NewUPowerProvider 创建使用系统总线的 UPower 提供者
+func NewUPowerProvider() *UPowerProvider {
+	return &UPowerProvider{Bus: ExecBus{}}
+}
+
+// Devices 实现 Provider，按 EnumerateDevices 的顺序返回设备
+func (p *UPowerProvider) Devices() ([]*BatteryInfo, error) {
+	paths, err := p.Bus.ObjectPaths(upowerService, upowerPath, upowerService, "EnumerateDevices")
+	if err != nil {
+		return nil, fmt.Errorf("查询 UPower 失败: %w", err)
+	}
+
+	var devices []*BatteryInfo
+	for _, objectPath := range paths {
+		props, err := p.Bus.Properties(upowerService, objectPath, upowerDevice)
+		if err != nil {
+			// 设备可能在枚举之后被移除
+			continue
+		}
+		if info, ok := upowerDeviceInfo(objectPath, props); ok {
+			devices = append(devices, info)
+		}
+	}
+	return devices, nil
+}
+
+// Watch 订阅 UPower 的设备变化，设备属性变化、添加或移除时向返回的通道发送通知
+// 短时间内的多次变化合并为一次通知；stop 关闭或订阅中断后关闭通道
+func (p *UPowerProvider) Watch(stop <-chan struct{}) (<-chan struct{}, error) {
+	signals, err := p.Bus.Subscribe(upowerService, stop)
+	if err != nil {
+		return nil, fmt.Errorf("订阅 UPower 失败: %w", err)
+	}
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer close(changes)
+		for sig := range signals {
+			if !upowerChange(sig) {
+				continue
+			}
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// upowerChange 判断信号是否表示设备列表或设备电量发生变化
+func upowerChange(sig Signal) bool {
+	switch sig.Member {
+	case propertiesChanged:
+		return sig.Interface == upowerDevice
+	case "DeviceAdded", "DeviceRemoved":
+		return sig.Path == upowerPath
+	}
+	return false
+}
+
+// upowerDeviceInfo 将 UPower 设备属性转换为电池信息，不关心的设备类型返回 false
+func upowerDeviceInfo(objectPath string, props map[string]any) (*BatteryInfo, bool) {
+	typ, _ := props["Type"].(float64)
+	kind, ok := upowerKinds[uint32(typ)]
+	if !ok {
+		return nil, false
+	}
+	if present, ok := props["IsPresent"].(bool); ok && !present {
+		return nil, false
+	}
+
+	percentage, _ := props["Percentage"].(float64)
+	level, _ := props["BatteryLevel"].(float64)
+	if p, ok := upowerLevels[uint32(level)]; ok {
+		percentage = float64(p)
+	}
+
+	state, _ := props["State"].(float64)
+	timeToEmpty, _ := props["TimeToEmpty"].(float64)
+
+	name, _ := props["Model"].(string)
+	if name == "" {
+		name = path.Base(objectPath)
+	}
+	serial, _ := props["Serial"].(string)
+	nativePath, _ := props["NativePath"].(string)
+
+	info := &BatteryInfo{
+		Percentage:  int(percentage + 0.5),
+		State:       upowerStates[uint32(state)],
+		Available:   true,
+		Name:        name,
+		Serial:      serial,
+		Kind:        kind,
+		TimeToEmpty: time.Duration(timeToEmpty) * time.Second,
+	}
+	info.IsCharging = info.State == StateCharging
+
+	// 蓝牙设备的序列号是设备地址，与 BlueZ 报告的同一设备去重
+	if strings.HasPrefix(nativePath, "/org/bluez/") {
+		info.Address = serial
+	} else if addr := hidBatteryAddress(nativePath); addr != "" {
+		info.Address = addr
+	}
+	return info, true
+}
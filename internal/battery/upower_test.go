@@ -0,0 +1,166 @@
+package battery
+
+import (
+	"testing"
+	"time"
+)
+
+// newUPowerBus 返回包含鼠标、笔记本电池、只报告粗略电量的键盘和电源适配器的 UPower 总线
+func newUPowerBus() *fakeBus {
+	return &fakeBus{
+		paths: []string{
+			"/org/freedesktop/UPower/devices/mouse_dev_AA_BB_CC_DD_EE_01",
+			"/org/freedesktop/UPower/devices/battery_BAT0",
+			"/org/freedesktop/UPower/devices/keyboard_hidpp_battery_1",
+			"/org/freedesktop/UPower/devices/line_power_AC",
+			"/org/freedesktop/UPower/devices/gone",
+		},
+		properties: map[string]map[string]any{
+			"/org/freedesktop/UPower/devices/mouse_dev_AA_BB_CC_DD_EE_01": {
+				"Type": float64(5), "Model": "MX Master 3", "Serial": "AA:BB:CC:DD:EE:01",
+				"NativePath": "/org/bluez/hci0/dev_AA_BB_CC_DD_EE_01",
+				"Percentage": float64(84.6), "State": float64(2), "TimeToEmpty": float64(7200),
+				"BatteryLevel": float64(1), "IsPresent": true,
+			},
+			"/org/freedesktop/UPower/devices/battery_BAT0": {
+				"Type": float64(2), "Model": "", "NativePath": "BAT0",
+				"Percentage": float64(100), "State": float64(4), "IsPresent": true,
+			},
+			"/org/freedesktop/UPower/devices/keyboard_hidpp_battery_1": {
+				"Type": float64(6), "Model": "K380", "Serial": "1234-5678", "NativePath": "hidpp_battery_1",
+				"Percentage": float64(0), "State": float64(5), "BatteryLevel": float64(3),
+			},
+			"/org/freedesktop/UPower/devices/line_power_AC": {
+				"Type": float64(1), "NativePath": "AC",
+			},
+		},
+	}
+}
+
+func TestUPowerProvider(t *testing.T) {
+	bus := newUPowerBus()
+	devices, err := (&UPowerProvider{Bus: bus}).Devices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bus.service != "org.freedesktop.UPower" {
+		t.Errorf("查询的服务 = %q", bus.service)
+	}
+	// 电源适配器被跳过，枚举后被移除的设备被忽略
+	if len(devices) != 3 {
+		t.Fatalf("应该有 3 个设备，实际 %d 个", len(devices))
+	}
+
+	mouse, bat, keyboard := devices[0], devices[1], devices[2]
+	if mouse.Name != "MX Master 3" || mouse.Percentage != 85 || mouse.Class() != ClassMouse ||
+		mouse.State != StateDischarging || mouse.TimeToEmpty != 2*time.Hour {
+		t.Errorf("鼠标信息错误: %+v", mouse)
+	}
+	if mouse.Address != "AA:BB:CC:DD:EE:01" {
+		t.Errorf("蓝牙设备的地址应该取自序列号, Address = %q", mouse.Address)
+	}
+
+	// 没有型号时使用对象路径的最后一段
+	if bat.Name != "battery_BAT0" || bat.State != StateFull || bat.Percentage != 100 || bat.Address != "" {
+		t.Errorf("电池信息错误: %+v", bat)
+	}
+
+	// 只报告粗略电量的设备使用近似百分比
+	if keyboard.Percentage != 10 || keyboard.Class() != ClassKeyboard || keyboard.State != StateNotCharging || keyboard.IsCharging {
+		t.Errorf("键盘信息错误: %+v", keyboard)
+	}
+}
+
+func TestCollectDeduplicatesAddress(t *testing.T) {
+	upower := &UPowerProvider{Bus: newUPowerBus()}
+	bluez := &BlueZProvider{Bus: &fakeBus{objects: ManagedObjects{
+		"/org/bluez/hci0/dev_AA_BB_CC_DD_EE_01": {
+			"org.bluez.Device1":  {"Alias": "MX Master 3", "Address": "AA:BB:CC:DD:EE:01", "Icon": "input-mouse"},
+			"org.bluez.Battery1": {"Percentage": float64(85)},
+		},
+		"/org/bluez/hci0/dev_AA_BB_CC_DD_EE_02": {
+			"org.bluez.Device1":  {"Alias": "MX Keys", "Address": "aa-bb-cc-dd-ee-02", "Icon": "input-keyboard"},
+			"org.bluez.Battery1": {"Percentage": float64(64)},
+		},
+	}}}
+
+	devices, err := Collect(upower, bluez)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// UPower 的 3 个设备加上 BlueZ 中 UPower 没有报告的键盘
+	if len(devices) != 4 {
+		t.Fatalf("应该有 4 个设备，实际 %d 个", len(devices))
+	}
+	if devices[0].TimeToEmpty == 0 || devices[3].Name != "MX Keys" {
+		t.Errorf("同一设备应该保留 UPower 的结果: %+v, %+v", devices[0], devices[3])
+	}
+}
+
+func TestUPowerWatch(t *testing.T) {
+	bus := newUPowerBus()
+	bus.signals = make(chan Signal)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	changes, err := Watch(stop, IORegProvider{}, &UPowerProvider{Bus: bus})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(sig Signal) {
+		select {
+		case bus.signals <- sig:
+		case <-time.After(time.Second):
+			t.Fatal("发送信号超时")
+		}
+	}
+	expect := func(want bool) {
+		t.Helper()
+		select {
+		case <-changes:
+			if !want {
+				t.Error("不应收到通知")
+			}
+		case <-time.After(100 * time.Millisecond):
+			if want {
+				t.Error("应该收到通知")
+			}
+		}
+	}
+
+	// 其他接口的属性变化不通知
+	send(Signal{Path: "/org/freedesktop/UPower", Interface: "org.freedesktop.UPower", Member: "PropertiesChanged"})
+	expect(false)
+
+	send(Signal{
+		Path:      "/org/freedesktop/UPower/devices/mouse_dev_AA_BB_CC_DD_EE_01",
+		Interface: "org.freedesktop.UPower.Device",
+		Member:    "PropertiesChanged",
+	})
+	expect(true)
+
+	// 短时间内的多次变化合并为一次通知
+	send(Signal{Path: "/org/freedesktop/UPower", Interface: "org.freedesktop.UPower", Member: "DeviceAdded"})
+	send(Signal{Path: "/org/freedesktop/UPower", Interface: "org.freedesktop.UPower", Member: "DeviceRemoved"})
+	time.Sleep(20 * time.Millisecond)
+	expect(true)
+	expect(false)
+
+	// 订阅结束后关闭通道
+	close(bus.signals)
+	select {
+	case _, ok := <-changes:
+		if ok {
+			t.Error("订阅结束后通道应该关闭")
+		}
+	case <-time.After(time.Second):
+		t.Error("订阅结束后通道应该关闭")
+	}
+}
+
+func TestWatchUnsupported(t *testing.T) {
+	if _, err := Watch(nil, IORegProvider{}); err == nil {
+		t.Error("没有支持推送的提供者时应该返回错误")
+	}
+}
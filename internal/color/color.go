@@ -0,0 +1,248 @@
+// Package color 实现 tmux 颜色语法的解析与转换
+package color
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Kind 表示颜色的类型
+type Kind int
+
+const (
+	// KindDefault 使用终端默认颜色（default/terminal）
+	KindDefault Kind = iota
+	// KindANSI 基本 16 色（black..white, brightblack..brightwhite）
+	KindANSI
+	// KindIndexed 256 色调色板（colourN/colorN）
+	KindIndexed
+	// KindRGB 真彩色（#RRGGBB）
+	KindRGB
+)
+
+// Color 表示解析后的 tmux 颜色
+type Color struct {
+	Kind  Kind
+	Index int
+	RGB   RGB
+	// Terminal 区分 terminal 与 default，两者都属于 KindDefault
+	Terminal bool
+}
+
+// RGB 表示一个真彩色值
+type RGB struct {
+	R, G, B uint8
+}
+
+// Hex 返回 #rrggbb 形式的颜色
+func (c RGB) Hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// ansiNames 基本颜色名，下标即 ANSI 颜色编号
+var ansiNames = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// Parse 按 tmux 的颜色语法解析颜色字符串
+func Parse(s string) (Color, error) {
+	s = strings.TrimSpace(s)
+	lower := strings.ToLower(s)
+
+	switch lower {
+	case "default":
+		return Color{Kind: KindDefault}, nil
+	case "terminal":
+		return Color{Kind: KindDefault, Terminal: true}, nil
+	}
+
+	for i, name := range ansiNames {
+		// tmux 同时接受 0-7 和 90-97 作为基本色和亮色的别名
+		if lower == name || lower == strconv.Itoa(i) {
+			return Color{Kind: KindANSI, Index: i}, nil
+		}
+		if lower == "bright"+name || lower == strconv.Itoa(90+i) {
+			return Color{Kind: KindANSI, Index: 8 + i}, nil
+		}
+	}
+
+	for _, prefix := range []string{"colour", "color"} {
+		if strings.HasPrefix(lower, prefix) {
+			n, err := strconv.Atoi(lower[len(prefix):])
+			if err != nil || n < 0 || n > 255 {
+				return Color{}, fmt.Errorf("无效的颜色编号: %q", s)
+			}
+			return Color{Kind: KindIndexed, Index: n}, nil
+		}
+	}
+
+	if len(s) == 7 && s[0] == '#' {
+		v, err := strconv.ParseUint(s[1:], 16, 32)
+		if err == nil {
+			return Color{Kind: KindRGB, RGB: RGB{uint8(v >> 16), uint8(v >> 8), uint8(v)}}, nil
+		}
+	}
+
+	return Color{}, fmt.Errorf("无效的颜色: %q", s)
+}
+
+// MustParse 解析颜色，失败时返回终端默认颜色
+func MustParse(s string) Color {
+	c, err := Parse(s)
+	if err != nil {
+		return Color{Kind: KindDefault}
+	}
+	return c
+}
+
+// FromRGB 由真彩色值构造颜色
+func FromRGB(c RGB) Color {
+	return Color{Kind: KindRGB, RGB: c}
+}
+
+// String 返回 tmux 格式的颜色字符串
+func (c Color) String() string {
+	switch c.Kind {
+	case KindANSI:
+		if c.Index >= 8 {
+			return "bright" + ansiNames[c.Index-8]
+		}
+		return ansiNames[c.Index]
+	case KindIndexed:
+		return fmt.Sprintf("colour%d", c.Index)
+	case KindRGB:
+		return c.RGB.Hex()
+	}
+
+	if c.Terminal {
+		return "terminal"
+	}
+	return "default"
+}
+
+// IsDefault 判断是否为终端默认颜色
+func (c Color) IsDefault() bool {
+	return c.Kind == KindDefault
+}
+
+// Lipgloss 转换为 lipgloss 颜色，默认颜色对应 lipgloss.NoColor
+func (c Color) Lipgloss() lipgloss.TerminalColor {
+	switch c.Kind {
+	case KindANSI, KindIndexed:
+		return lipgloss.Color(strconv.Itoa(c.Index))
+	case KindRGB:
+		return lipgloss.Color(c.RGB.Hex())
+	}
+	return lipgloss.NoColor{}
+}
+
+// SGR 返回 ANSI SGR 颜色参数，bg 为 true 时返回背景色参数
+// 默认颜色返回 39/49
+func (c Color) SGR(bg bool) string {
+	base := 30
+	if bg {
+		base = 40
+	}
+
+	switch c.Kind {
+	case KindANSI:
+		if c.Index >= 8 {
+			return strconv.Itoa(base + 60 + c.Index - 8)
+		}
+		return strconv.Itoa(base + c.Index)
+	case KindIndexed:
+		return fmt.Sprintf("%d;5;%d", base+8, c.Index)
+	case KindRGB:
+		return fmt.Sprintf("%d;2;%d;%d;%d", base+8, c.RGB.R, c.RGB.G, c.RGB.B)
+	}
+	return strconv.Itoa(base + 9)
+}
+
+// ToRGB 返回颜色对应的真彩色值，默认颜色没有确定的 RGB 值
+func (c Color) ToRGB() (RGB, bool) {
+	switch c.Kind {
+	case KindANSI, KindIndexed:
+		return paletteRGB(c.Index), true
+	case KindRGB:
+		return c.RGB, true
+	}
+	return RGB{}, false
+}
+
+// Downsample 将真彩色转换为最接近的 256 色，其他类型保持不变
+func (c Color) Downsample() Color {
+	if c.Kind != KindRGB {
+		return c
+	}
+	return Color{Kind: KindIndexed, Index: Nearest256(c.RGB)}
+}
+
+// ansiPalette xterm 默认的 16 色调色板
+var ansiPalette = [16]RGB{
+	{0x00, 0x00, 0x00}, {0xcd, 0x00, 0x00}, {0x00, 0xcd, 0x00}, {0xcd, 0xcd, 0x00},
+	{0x00, 0x00, 0xee}, {0xcd, 0x00, 0xcd}, {0x00, 0xcd, 0xcd}, {0xe5, 0xe5, 0xe5},
+	{0x7f, 0x7f, 0x7f}, {0xff, 0x00, 0x00}, {0x00, 0xff, 0x00}, {0xff, 0xff, 0x00},
+	{0x5c, 0x5c, 0xff}, {0xff, 0x00, 0xff}, {0x00, 0xff, 0xff}, {0xff, 0xff, 0xff},
+}
+
+// cubeLevels xterm 256 色 6x6x6 色块每个通道的取值
+var cubeLevels = [6]int{0x00, 0x5f, 0x87, 0xaf, 0xd7, 0xff}
+
+// paletteRGB 返回 256 色调色板中指定索引的 RGB 值
+func paletteRGB(index int) RGB {
+	switch {
+	case index < 16:
+		return ansiPalette[index]
+	case index < 232:
+		i := index - 16
+		return RGB{uint8(cubeLevels[i/36]), uint8(cubeLevels[(i/6)%6]), uint8(cubeLevels[i%6])}
+	default:
+		level := uint8(8 + 10*(index-232))
+		return RGB{level, level, level}
+	}
+}
+
+// Nearest256 返回与给定颜色最接近的 xterm 256 色索引
+func Nearest256(c RGB) int {
+	cubeIndex := func(v uint8) int {
+		best := 0
+		for i, level := range cubeLevels {
+			if absInt(int(v)-level) < absInt(int(v)-cubeLevels[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+
+	// 色块中最接近的颜色
+	ri, gi, bi := cubeIndex(c.R), cubeIndex(c.G), cubeIndex(c.B)
+	cubeColor := 16 + 36*ri + 6*gi + bi
+
+	// 灰阶中最接近的颜色 (232-255)
+	avg := (int(c.R) + int(c.G) + int(c.B)) / 3
+	grayIndex := 23
+	if avg < 238 {
+		grayIndex = max(0, (avg-3)/10)
+	}
+
+	if distance(c, paletteRGB(232+grayIndex)) < distance(c, paletteRGB(cubeColor)) {
+		return 232 + grayIndex
+	}
+	return cubeColor
+}
+
+// distance 计算两个颜色的欧氏距离平方
+func distance(a, b RGB) int {
+	dr := int(a.R) - int(b.R)
+	dg := int(a.G) - int(b.G)
+	db := int(a.B) - int(b.B)
+	return dr*dr + dg*dg + db*db
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,120 @@
+package color
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		kind  Kind
+		index int
+		rgb   RGB
+		str   string
+	}{
+		{"red", KindANSI, 1, RGB{}, "red"},
+		{"White", KindANSI, 7, RGB{}, "white"},
+		{"3", KindANSI, 3, RGB{}, "yellow"},
+		{"brightred", KindANSI, 9, RGB{}, "brightred"},
+		{"brightwhite", KindANSI, 15, RGB{}, "brightwhite"},
+		{"94", KindANSI, 12, RGB{}, "brightblue"},
+		{"colour214", KindIndexed, 214, RGB{}, "colour214"},
+		{"color0", KindIndexed, 0, RGB{}, "colour0"},
+		{"Colour255", KindIndexed, 255, RGB{}, "colour255"},
+		{"#FF8800", KindRGB, 0, RGB{0xff, 0x88, 0x00}, "#ff8800"},
+		{"#7d56f4", KindRGB, 0, RGB{0x7d, 0x56, 0xf4}, "#7d56f4"},
+		{"default", KindDefault, 0, RGB{}, "default"},
+		{"terminal", KindDefault, 0, RGB{}, "terminal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			c, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("解析颜色失败: %v", err)
+			}
+			if c.Kind != tt.kind {
+				t.Errorf("Kind = %v，期望 %v", c.Kind, tt.kind)
+			}
+			if c.Index != tt.index {
+				t.Errorf("Index = %d，期望 %d", c.Index, tt.index)
+			}
+			if c.RGB != tt.rgb {
+				t.Errorf("RGB = %+v，期望 %+v", c.RGB, tt.rgb)
+			}
+			if c.String() != tt.str {
+				t.Errorf("String() = %q，期望 %q", c.String(), tt.str)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{"", "purple", "colour256", "colour-1", "colourx", "#12345", "#gggggg", "brightorange"} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("无效颜色 %q 应该返回错误", input)
+		}
+	}
+}
+
+func TestLipgloss(t *testing.T) {
+	tests := []struct {
+		input string
+		want  lipgloss.TerminalColor
+	}{
+		{"red", lipgloss.Color("1")},
+		{"brightred", lipgloss.Color("9")},
+		{"colour214", lipgloss.Color("214")},
+		{"#FF8800", lipgloss.Color("#ff8800")},
+		{"default", lipgloss.NoColor{}},
+		{"terminal", lipgloss.NoColor{}},
+	}
+
+	for _, tt := range tests {
+		if got := MustParse(tt.input).Lipgloss(); got != tt.want {
+			t.Errorf("Lipgloss(%q) = %#v，期望 %#v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestToRGB(t *testing.T) {
+	tests := []struct {
+		input string
+		want  RGB
+		ok    bool
+	}{
+		{"brightred", RGB{0xff, 0x00, 0x00}, true},
+		{"colour196", RGB{0xff, 0x00, 0x00}, true},
+		{"colour244", RGB{0x80, 0x80, 0x80}, true},
+		{"#010203", RGB{0x01, 0x02, 0x03}, true},
+		{"default", RGB{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := MustParse(tt.input).ToRGB()
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ToRGB(%q) = %+v, %v，期望 %+v, %v", tt.input, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNearest256(t *testing.T) {
+	tests := []struct {
+		color RGB
+		want  int
+	}{
+		{RGB{0xff, 0x00, 0x00}, 196},
+		{RGB{0x00, 0xff, 0x00}, 46},
+		{RGB{0xff, 0xff, 0x00}, 226},
+		{RGB{0x80, 0x80, 0x80}, 244},
+		{RGB{0x00, 0x00, 0x00}, 16},
+	}
+
+	for _, tt := range tests {
+		if got := Nearest256(tt.color); got != tt.want {
+			t.Errorf("Nearest256(%s) = %d，期望 %d", tt.color.Hex(), got, tt.want)
+		}
+	}
+}
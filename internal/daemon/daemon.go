@@ -0,0 +1,88 @@
+// Package daemon 实现常驻模式的定时采样循环，采样结果分发给各个处理器
+package daemon
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/snapshot"
+)
+
+// Sink 接收每次采样的结果
+type Sink interface {
+	// Handle 处理一次采样，返回的错误只会被记录，不会中断采样循环
+	Handle(s *snapshot.Snapshot) error
+}
+
+// SinkFunc 将函数适配为 Sink
+type SinkFunc func(s *snapshot.Snapshot) error
+
+// Handle 实现 Sink
+func (f SinkFunc) Handle(s *snapshot.Snapshot) error {
+	return f(s)
+}
+
+// Daemon 按固定间隔采样并分发结果
+type Daemon struct {
+	Interval time.Duration
+	Collect  func() *snapshot.Snapshot
+	Sinks    []Sink
+	Logger   *log.Logger
+
+	// Trigger 收到通知时立即采样（如 battery.WatchDevices 推送的设备变化），为 nil 时只按间隔采样
+	Trigger <-chan struct{}
+}
+
+// New 创建新的采样循环，使用 snapshot.Collect 采样
+func New(interval time.Duration, sinks ...Sink) *Daemon {
+	return &Daemon{
+		Interval: interval,
+		Collect:  snapshot.Collect,
+		Sinks:    sinks,
+		Logger:   log.Default(),
+	}
+}
+
+// Run 立即采样一次，之后按间隔或 Trigger 的通知采样，直到 ctx 结束
+func (d *Daemon) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	trigger := d.Trigger
+	for {
+		d.sample()
+
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+				break wait
+			case _, ok := <-trigger:
+				if !ok {
+					// 推送中断后只按间隔采样，继续等待定时器而不是立即采样
+					trigger = nil
+					if d.Logger != nil {
+						d.Logger.Printf("设备变化推送已中断，按间隔采样")
+					}
+					continue
+				}
+				// 推送的采样之后重新计时，避免紧接着再采样一次
+				ticker.Reset(d.Interval)
+				break wait
+			}
+		}
+	}
+}
+
+// sample 采样一次并分发给所有处理器
+func (d *Daemon) sample() {
+	s := d.Collect()
+	for _, sink := range d.Sinks {
+		if err := sink.Handle(s); err != nil && d.Logger != nil {
+			d.Logger.Printf("处理采样结果失败: %v", err)
+		}
+	}
+}
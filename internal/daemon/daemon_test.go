@@ -0,0 +1,36 @@
+package daemon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/snapshot"
+)
+
+func TestRunTriggerClosed(t *testing.T) {
+	trigger := make(chan struct{}, 1)
+	trigger <- struct{}{}
+	close(trigger)
+
+	samples := 0
+	d := &Daemon{
+		Interval: time.Hour,
+		Collect: func() *snapshot.Snapshot {
+			samples++
+			return &snapshot.Snapshot{}
+		},
+		Trigger: trigger,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := d.Run(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	// 启动时一次、推送一次，推送中断后等待定时器而不是立即再采样
+	if samples != 2 {
+		t.Errorf("采样 %d 次，期望 2 次", samples)
+	}
+}
@@ -0,0 +1,145 @@
+package display
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/color"
+	"github.com/akayj/tmux-touchpad-battery/internal/style"
+)
+
+// BarFormatter 将电池和系统信息输出为 i3bar、Waybar 和 Polybar 格式
+// 阈值和颜色逻辑与 tmux 输出共用，同一份配置驱动所有状态栏
+type BarFormatter struct {
+	battery BatterySource
+	system  *SystemInfoFormatter
+}
+
+// BatterySource 电池片段的来源：单个触摸板（BatteryFormatter）或多个设备（DeviceListFormatter）
+type BatterySource interface {
+	SegmentFormatter
+
+	// Percentage 返回 Waybar 使用的电量，没有可用设备时 ok 为 false
+	Percentage() (percent int, ok bool)
+}
+
+// NewBarFormatter 创建新的状态栏格式化器
+func NewBarFormatter(battery BatterySource, system *SystemInfoFormatter) *BarFormatter {
+	return &BarFormatter{
+		battery: battery,
+		system:  system,
+	}
+}
+
+// Segments 返回所有片段
+func (f *BarFormatter) Segments() []Segment {
+	return append(f.battery.Segments(), f.system.Segments()...)
+}
+
+// I3barBlock 表示 i3bar 协议中的一个块
+type I3barBlock struct {
+	Name       string `json:"name"`
+	FullText   string `json:"full_text"`
+	Color      string `json:"color,omitempty"`
+	Background string `json:"background,omitempty"`
+	Urgent     bool   `json:"urgent,omitempty"`
+}
+
+// I3barBlocks 返回 i3bar 块，低电量闪烁对应 urgent
+func (f *BarFormatter) I3barBlocks() []I3barBlock {
+	blocks := []I3barBlock{}
+	for _, seg := range f.Segments() {
+		blocks = append(blocks, I3barBlock{
+			Name:       seg.Name,
+			FullText:   seg.Text,
+			Color:      hexColor(seg.Style.Fg),
+			Background: hexColor(seg.Style.Bg),
+			Urgent:     seg.Style.Attrs&style.AttrBlink != 0,
+		})
+	}
+	return blocks
+}
+
+// I3barWriter 按 i3bar 流式协议输出，首次写入时输出协议头和无限数组的开头
+type I3barWriter struct {
+	w       io.Writer
+	started bool
+}
+
+// NewI3barWriter 创建新的 i3bar 输出器
+func NewI3barWriter(w io.Writer) *I3barWriter {
+	return &I3barWriter{w: w}
+}
+
+// Write 输出一行状态
+func (iw *I3barWriter) Write(blocks []I3barBlock) error {
+	if !iw.started {
+		if _, err := io.WriteString(iw.w, "{\"version\":1}\n[\n"); err != nil {
+			return err
+		}
+		iw.started = true
+	}
+
+	data, err := json.Marshal(blocks)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(iw.w, "%s,\n", data)
+	return err
+}
+
+// WaybarOutput 表示 Waybar 自定义模块的 JSON 输出
+type WaybarOutput struct {
+	Text       string   `json:"text"`
+	Tooltip    string   `json:"tooltip"`
+	Class      []string `json:"class"`
+	Percentage int      `json:"percentage"`
+}
+
+// Waybar 返回 Waybar 自定义模块输出
+// text 使用 Pango 标记着色，class 包含电量级别以便在 CSS 中匹配
+func (f *BarFormatter) Waybar() WaybarOutput {
+	out := WaybarOutput{Class: []string{}}
+
+	var texts, tooltips []string
+	for _, seg := range f.Segments() {
+		text := html.EscapeString(seg.Text)
+		if fg := hexColor(seg.Style.Fg); fg != "" {
+			text = fmt.Sprintf("<span color=\"%s\">%s</span>", fg, text)
+		}
+		texts = append(texts, text)
+		tooltips = append(tooltips, seg.Text)
+
+		if seg.Class != "" {
+			out.Class = append(out.Class, seg.Class)
+		}
+	}
+
+	if percent, ok := f.battery.Percentage(); ok {
+		out.Percentage = percent
+	}
+
+	out.Text = strings.Join(texts, " ")
+	out.Tooltip = strings.Join(tooltips, "\n")
+	return out
+}
+
+// Polybar 返回带 Polybar 格式标签的输出
+func (f *BarFormatter) Polybar() string {
+	return PolybarSerializer{}.Serialize(f.Segments())
+}
+
+// hexColor 将颜色转换为 #rrggbb，未设置或默认颜色返回空字符串
+func hexColor(c *color.Color) string {
+	if c == nil {
+		return ""
+	}
+	rgb, ok := c.ToRGB()
+	if !ok {
+		return ""
+	}
+	return rgb.Hex()
+}
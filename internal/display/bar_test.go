@@ -0,0 +1,75 @@
+package display
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+	"github.com/akayj/tmux-touchpad-battery/internal/system"
+)
+
+func newTestBarFormatter(percentage int) *BarFormatter {
+	config := newTestConfig()
+	config.ColorStress = "#ff0000"
+	config.BlinkOnLowBattery = true
+
+	bf := NewBatteryFormatter(config)
+	bf.SetBatteryInfo(&battery.BatteryInfo{Percentage: percentage, Available: true})
+	sf := NewSystemFormatter(config)
+	sf.SetSystemInfo(&system.SystemInfo{CPUUsage: 12.5, Available: true})
+
+	return NewBarFormatter(bf, sf)
+}
+
+func TestBarI3bar(t *testing.T) {
+	blocks := newTestBarFormatter(20).I3barBlocks()
+	if len(blocks) != 2 {
+		t.Fatalf("应该有 2 个块，实际: %d", len(blocks))
+	}
+
+	if blocks[0].Name != SegmentBattery || blocks[0].Color != "#ff0000" || !blocks[0].Urgent {
+		t.Errorf("电池块错误: %+v", blocks[0])
+	}
+	if blocks[1].Name != SegmentCPU || blocks[1].Urgent {
+		t.Errorf("CPU 块错误: %+v", blocks[1])
+	}
+
+	var buf bytes.Buffer
+	writer := NewI3barWriter(&buf)
+	_ = writer.Write(blocks)
+	_ = writer.Write(blocks)
+
+	lines := strings.Split(buf.String(), "\n")
+	if lines[0] != `{"version":1}` || lines[1] != "[" {
+		t.Errorf("i3bar 协议头错误: %q", buf.String())
+	}
+	if !strings.HasPrefix(lines[2], `[{"name":"battery"`) || !strings.HasSuffix(lines[3], "],") {
+		t.Errorf("i3bar 状态行错误: %q", buf.String())
+	}
+}
+
+func TestBarWaybar(t *testing.T) {
+	out := newTestBarFormatter(20).Waybar()
+
+	if out.Percentage != 20 {
+		t.Errorf("percentage = %d，期望 20", out.Percentage)
+	}
+	if len(out.Class) != 1 || out.Class[0] != "stress" {
+		t.Errorf("class = %v，期望 [stress]", out.Class)
+	}
+	if !strings.HasPrefix(out.Text, `<span color="#ff0000">TP:20%</span>`) {
+		t.Errorf("text 错误: %q", out.Text)
+	}
+	if out.Tooltip != "TP:20%\nCPU:12.5%" {
+		t.Errorf("tooltip 错误: %q", out.Tooltip)
+	}
+}
+
+func TestBarPolybar(t *testing.T) {
+	got := newTestBarFormatter(50).Polybar()
+	want := "%{F#cdcd00}TP:50%%{F-} %{F#e5e5e5}CPU:12.5%%{F-}"
+	if got != want {
+		t.Errorf("Polybar() = %q，期望 %q", got, want)
+	}
+}
@@ -2,92 +2,307 @@ package display
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 
 	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+	"github.com/akayj/tmux-touchpad-battery/internal/color"
+	"github.com/akayj/tmux-touchpad-battery/internal/history"
+	"github.com/akayj/tmux-touchpad-battery/internal/presence"
+	"github.com/akayj/tmux-touchpad-battery/internal/style"
 	"github.com/akayj/tmux-touchpad-battery/internal/tmux"
 )
 
+// Level 表示电池电量所处的显示级别
+type Level int
+
+const (
+	LevelHidden Level = iota
+	LevelCharging
+	LevelHigh
+	LevelMedium
+	LevelStress
+	// LevelFull 已充满并接通电源
+	LevelFull
+)
+
+// ClassDisconnected 断开设备片段的类别
+const ClassDisconnected = "disconnected"
+
+// String 返回级别名称
+func (l Level) String() string {
+	switch l {
+	case LevelCharging:
+		return "charging"
+	case LevelHigh:
+		return "high"
+	case LevelMedium:
+		return "medium"
+	case LevelStress:
+		return "stress"
+	case LevelFull:
+		return "full"
+	}
+	return "hidden"
+}
+
 // BatteryFormatter 负责格式化电池显示
 type BatteryFormatter struct {
+	// config 为应用了当前设备覆盖后的配置，base 为原始配置
 	config      *tmux.Config
+	base        *tmux.Config
 	batteryInfo *battery.BatteryInfo
+	gradient    []GradientStop
+	styles      map[Level]style.Style
+	errs        []error
+
+	// 预计剩余时间，未知时为 0
+	eta time.Duration
+
+	// 触摸板断开时最后一次出现的状态，未知时为 nil
+	lastSeen *presence.Record
+
+	// 启用级别回差时的级别状态，levelInfo 为计算该状态时的电池信息
+	levelState *LevelState
+	levelInfo  *battery.BatteryInfo
+	now        func() time.Time
 }
 
 // NewBatteryFormatter 创建新的电池格式化器
 func NewBatteryFormatter(config *tmux.Config) *BatteryFormatter {
-	return &BatteryFormatter{
+	f := &BatteryFormatter{
 		config: config,
+		base:   config,
+		styles: make(map[Level]style.Style),
 	}
+
+	// 渐变节点无效时回退到阈值模式
+	if config.ColorMode == ColorModeGradient {
+		stops, err := ParseGradientStops(config.GradientStops)
+		if err != nil {
+			f.errs = append(f.errs, fmt.Errorf("@tpb_gradient_stops: %w", err))
+		} else {
+			f.gradient = stops
+		}
+	}
+
+	// 校验颜色配置，无效颜色按终端默认颜色处理
+	for _, opt := range []struct{ option, value string }{
+		{"@tpb_color_charging", config.ColorCharging},
+		{"@tpb_color_full", config.ColorFull},
+		{"@tpb_color_high", config.ColorHigh},
+		{"@tpb_color_medium", config.ColorMedium},
+		{"@tpb_color_stress", config.ColorStress},
+	} {
+		if _, err := color.Parse(opt.value); err != nil {
+			f.errs = append(f.errs, fmt.Errorf("%s: %w", opt.option, err))
+		}
+	}
+
+	// 解析每个级别的样式，无效样式会被忽略并记录错误
+	for _, opt := range []struct {
+		level         Level
+		option, value string
+	}{
+		{LevelCharging, "@tpb_style_charging", config.StyleCharging},
+		{LevelFull, "@tpb_style_full", config.StyleFull},
+		{LevelHigh, "@tpb_style_high", config.StyleHigh},
+		{LevelMedium, "@tpb_style_medium", config.StyleMedium},
+		{LevelStress, "@tpb_style_stress", config.StyleStress},
+	} {
+		if opt.value == "" {
+			continue
+		}
+		st, err := style.Parse(opt.value)
+		if err != nil {
+			f.errs = append(f.errs, fmt.Errorf("%s: %w", opt.option, err))
+			continue
+		}
+		f.styles[opt.level] = st
+	}
+
+	return f
 }
 
-// SetBatteryInfo 设置电池信息
+// Errors 返回解析配置时遇到的错误
+func (f *BatteryFormatter) Errors() []error {
+	return f.errs
+}
+
+// SetBatteryInfo 设置电池信息，按设备类别和名称应用设备覆盖配置，启用级别回差时同时更新级别状态
 func (f *BatteryFormatter) SetBatteryInfo(info *battery.BatteryInfo) {
 	f.batteryInfo = info
+	f.config = f.base
+	if info != nil && info.Available {
+		f.config = forDevice(f.base, info)
+	}
+	f.applyHysteresis(info)
 }
 
-// Format 格式化电池信息为 tmux 状态栏显示
-func (f *BatteryFormatter) Format() string {
-	if f.batteryInfo == nil || !f.batteryInfo.Available {
-		return ""
+// forDevice 应用设备覆盖配置
+// 前缀仍为默认的 "Touchpad:" 时，非触摸板设备改用设备名称（没有名称时用类别）作为前缀
+func forDevice(base *tmux.Config, info *battery.BatteryInfo) *tmux.Config {
+	class := info.Class()
+	config := base.ForDevice(class, info.Name)
+	if class == battery.ClassTrackpad || config.PercentPrefix != tmux.DefaultPercentPrefix {
+		return config
 	}
 
-	// 如果电量达到不显示阈值，则不显示
-	if f.batteryInfo.Percentage >= f.config.NotShowThreshold {
-		return ""
+	label := info.Name
+	if label == "" {
+		label = class
 	}
+	resolved := *config
+	resolved.PercentPrefix = label + ":"
+	return &resolved
+}
+
+// SetETA 设置预计剩余时间，0 表示未知
+func (f *BatteryFormatter) SetETA(eta time.Duration) {
+	f.eta = eta
+}
+
+// SetLastSeen 设置断开设备最后一次出现的状态，nil 表示未知
+func (f *BatteryFormatter) SetLastSeen(r *presence.Record) {
+	f.lastSeen = r
+}
 
-	color := f.getBatteryColor(f.batteryInfo)
-	if color == "" {
-		return ""
+// Percentage 返回触摸板电量，未连接时 ok 为 false
+func (f *BatteryFormatter) Percentage() (int, bool) {
+	if f.batteryInfo == nil || !f.batteryInfo.Available {
+		return 0, false
 	}
+	return f.batteryInfo.Percentage, true
+}
 
-	// 检查是否需要闪烁（低电量且未充电时）
-	blinkAttr := ""
-	if f.shouldBlink(f.batteryInfo) {
-		blinkAttr = ",blink"
+// UsesETA 判断显示格式是否引用了 {eta}，用于避免不必要的历史查询
+func (f *BatteryFormatter) UsesETA() bool {
+	return strings.Contains(f.config.Format, "{eta}")
+}
+
+// Format 格式化电池信息为 tmux 状态栏显示
+func (f *BatteryFormatter) Format() string {
+	// 格式化为 tmux 样式格式
+	return TmuxSerializer{}.Serialize(f.Segments())
+}
+
+// Segments 返回电池信息对应的样式片段
+func (f *BatteryFormatter) Segments() []Segment {
+	if f.batteryInfo == nil || !f.batteryInfo.Available {
+		return f.disconnectedSegments()
 	}
 
-	// 添加充电图标
-	chargingIcon := ""
-	if f.batteryInfo.IsCharging && f.config.ShowChargingIcon {
-		chargingIcon = f.config.ChargingIcon
+	// 如果电量达到不显示阈值，则不显示；充满并接通电源时按 @tpb_show_full 显示
+	level := f.getLevel(f.batteryInfo)
+	if level == LevelHidden {
+		return nil
+	}
+	if level != LevelFull && f.batteryInfo.Percentage >= f.config.NotShowThreshold {
+		return nil
 	}
 
-	// 格式化为 tmux 颜色格式
-	return fmt.Sprintf("#[fg=%s%s]%s%d%s%s",
-		color,
-		blinkAttr,
-		f.config.PercentPrefix,
-		f.batteryInfo.Percentage,
-		f.config.PercentSuffix,
-		chargingIcon,
-	)
+	return []Segment{{
+		Name:  SegmentBattery,
+		Text:  f.expandFormat(f.batteryInfo),
+		Style: f.getBatteryStyle(f.batteryInfo),
+		Class: level.String(),
+	}}
 }
 
 // FormatWithStyle 使用 lipgloss 格式化电池信息（用于终端显示）
 func (f *BatteryFormatter) FormatWithStyle() string {
 	if f.batteryInfo == nil || !f.batteryInfo.Available {
+		text := "Touchpad not connected"
+		if r := f.lastSeen; r != nil {
+			text += fmt.Sprintf(" (last seen %s at %d%%)", presence.FormatAgo(time.Since(r.LastSeen)), r.Percentage)
+		}
 		return lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")).
-			Render("Touchpad not connected")
+			Foreground(color.MustParse(f.config.ColorMuted).Lipgloss()).
+			Render(text)
 	}
 
-	color := f.getBatteryLipglossColor(f.batteryInfo)
-	style := lipgloss.NewStyle().Foreground(color)
-
 	text := fmt.Sprintf("%s%d%s",
 		f.config.PercentPrefix,
 		f.batteryInfo.Percentage,
 		f.config.PercentSuffix,
 	)
 
-	if f.batteryInfo.IsCharging && f.config.ShowChargingIcon {
-		text += " " + f.config.ChargingIcon
+	if icon := f.icon(f.batteryInfo); icon != "" {
+		text += " " + icon
+	}
+
+	return f.getBatteryStyle(f.batteryInfo).Lipgloss().Render(text)
+}
+
+// emptyBrackets 清理占位符为空后留下的括号
+var emptyBrackets = strings.NewReplacer("()", "", "[]", "")
+
+// expandFormat 展开 @tpb_format 中的占位符
+// 支持 {prefix}、{percent}、{suffix}、{icon} 和 {eta}，{eta} 未知时连同包裹它的括号一起省略
+func (f *BatteryFormatter) expandFormat(info *battery.BatteryInfo) string {
+	icon := f.icon(info)
+
+	// 没有历史估算时使用提供者报告的剩余时间
+	eta := ""
+	remaining := f.eta
+	if remaining == 0 {
+		remaining = info.TimeToEmpty
 	}
+	if remaining > 0 && !info.IsCharging && !info.State.PluggedIn() {
+		eta = history.FormatETA(remaining)
+	}
+
+	text := strings.NewReplacer(
+		"{prefix}", f.config.PercentPrefix,
+		"{percent}", strconv.Itoa(info.Percentage),
+		"{suffix}", f.config.PercentSuffix,
+		"{icon}", icon,
+		"{eta}", eta,
+	).Replace(f.config.Format)
+
+	if eta == "" && f.UsesETA() {
+		text = strings.TrimSpace(strings.ReplaceAll(emptyBrackets.Replace(text), "  ", " "))
+	}
+	return text
+}
 
-	return style.Render(text)
+// icon 返回充电图标，充满并接通电源时返回 @tpb_full_icon
+func (f *BatteryFormatter) icon(info *battery.BatteryInfo) string {
+	switch {
+	case info.IsCharging:
+		if f.config.ShowChargingIcon {
+			return f.config.ChargingIcon
+		}
+	case f.getLevel(info) == LevelFull:
+		return f.config.FullIcon
+	}
+	return ""
+}
+
+// disconnectedSegments 启用 @tpb_show_disconnected 时，将断开的设备渲染为暗色的最后状态
+func (f *BatteryFormatter) disconnectedSegments() []Segment {
+	if !f.config.ShowDisconnected || f.lastSeen == nil {
+		return nil
+	}
+
+	config := forDevice(f.base, &battery.BatteryInfo{Name: f.lastSeen.Name, Kind: f.lastSeen.Class})
+
+	text := strings.NewReplacer(
+		"{prefix}", config.PercentPrefix,
+		"{percent}", strconv.Itoa(f.lastSeen.Percentage),
+		"{suffix}", config.PercentSuffix,
+		"{ago}", presence.FormatAgo(time.Since(f.lastSeen.LastSeen)),
+	).Replace(f.config.DisconnectedFormat)
+
+	return []Segment{{
+		Name:  SegmentBattery,
+		Text:  text,
+		Style: style.Style{}.WithFg(color.MustParse(f.config.ColorMuted)).WithAttrs(style.AttrDim),
+		Class: ClassDisconnected,
+	}}
 }
 
 // FormatBattery 格式化指定的电池信息为 tmux 状态栏显示（向后兼容）
@@ -102,66 +317,87 @@ func (f *BatteryFormatter) FormatBatteryWithStyle(info *battery.BatteryInfo) str
 	return f.FormatWithStyle()
 }
 
-// getBatteryColor 获取电池颜色（tmux 格式）
-func (f *BatteryFormatter) getBatteryColor(info *battery.BatteryInfo) string {
+// getLevel 获取电池的显示级别，启用级别回差时使用 SetBatteryInfo 计算的结果
+func (f *BatteryFormatter) getLevel(info *battery.BatteryInfo) Level {
+	if f.levelInfo == info && f.levelState != nil {
+		return f.levelState.Level
+	}
+	return f.rawLevel(info)
+}
+
+// rawLevel 按阈值计算电池的显示级别
+func (f *BatteryFormatter) rawLevel(info *battery.BatteryInfo) Level {
 	if info.IsCharging {
-		return f.config.ColorCharging
+		return LevelCharging
+	}
+
+	if info.State == battery.StateFull && f.config.ShowFull {
+		return LevelFull
 	}
 
 	if info.Percentage < f.config.StressThreshold {
-		return f.config.ColorStress
+		return LevelStress
 	} else if info.Percentage < f.config.MediumThreshold {
-		return f.config.ColorMedium
+		return LevelMedium
 	} else if info.Percentage < f.config.NotShowThreshold {
-		return f.config.ColorHigh
+		return LevelHigh
 	}
 
-	return ""
+	return LevelHidden
 }
 
-// getBatteryLipglossColor 获取电池颜色（lipgloss 格式）
-func (f *BatteryFormatter) getBatteryLipglossColor(info *battery.BatteryInfo) lipgloss.Color {
-	if info.IsCharging {
-		return f.tmuxColorToLipgloss(f.config.ColorCharging)
+// getBatteryStyle 获取电池的完整样式
+// 级别样式未指定前景色时使用颜色配置（或渐变色），低电量闪烁会追加 blink 属性
+func (f *BatteryFormatter) getBatteryStyle(info *battery.BatteryInfo) style.Style {
+	st := f.styles[f.getLevel(info)]
+
+	if st.Fg == nil {
+		st = st.WithFg(f.getBatteryColor(info))
 	}
 
-	if info.Percentage < f.config.StressThreshold {
-		return f.tmuxColorToLipgloss(f.config.ColorStress)
-	} else if info.Percentage < f.config.MediumThreshold {
-		return f.tmuxColorToLipgloss(f.config.ColorMedium)
-	} else {
-		return f.tmuxColorToLipgloss(f.config.ColorHigh)
+	// 检查是否需要闪烁（低电量且未充电时）
+	if f.shouldBlink(info) {
+		st = st.WithAttrs(style.AttrBlink)
 	}
+
+	return st
 }
 
-// tmuxColorToLipgloss 将 tmux 颜色转换为 lipgloss 颜色
-func (f *BatteryFormatter) tmuxColorToLipgloss(tmuxColor string) lipgloss.Color {
-	colorMap := map[string]string{
-		"red":     "#FF0000",
-		"green":   "#00FF00",
-		"yellow":  "#FFFF00",
-		"blue":    "#0000FF",
-		"magenta": "#FF00FF",
-		"cyan":    "#00FFFF",
-		"white":   "#FFFFFF",
-		"black":   "#000000",
+// getBatteryColor 获取电池颜色
+func (f *BatteryFormatter) getBatteryColor(info *battery.BatteryInfo) color.Color {
+	if info.IsCharging {
+		return color.MustParse(f.config.ColorCharging)
+	}
+
+	if f.getLevel(info) == LevelFull {
+		return color.MustParse(f.config.ColorFull)
 	}
 
-	if color, exists := colorMap[tmuxColor]; exists {
-		return lipgloss.Color(color)
+	if f.gradient != nil {
+		c := color.FromRGB(gradientAt(f.gradient, info.Percentage))
+		if f.config.TrueColor {
+			return c
+		}
+		return c.Downsample()
 	}
 
-	// 如果是十六进制颜色或数字，直接返回
-	return lipgloss.Color(tmuxColor)
+	switch f.getLevel(info) {
+	case LevelStress:
+		return color.MustParse(f.config.ColorStress)
+	case LevelMedium:
+		return color.MustParse(f.config.ColorMedium)
+	default:
+		return color.MustParse(f.config.ColorHigh)
+	}
 }
 
 // shouldBlink 判断是否应该闪烁
 func (f *BatteryFormatter) shouldBlink(info *battery.BatteryInfo) bool {
 	// 只有在以下条件都满足时才闪烁：
 	// 1. 启用了闪烁功能
-	// 2. 电量低于压力阈值
+	// 2. 处于低电量级别（启用回差时与颜色一起保持稳定）
 	// 3. 没有在充电
 	return f.config.BlinkOnLowBattery &&
-		info.Percentage < f.config.StressThreshold &&
+		f.getLevel(info) == LevelStress &&
 		!info.IsCharging
 }
@@ -0,0 +1,184 @@
+package display
+
+import (
+	"testing"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+	"github.com/akayj/tmux-touchpad-battery/internal/presence"
+	"github.com/akayj/tmux-touchpad-battery/internal/tmux"
+)
+
+// newTestConfig 返回测试用的配置，前缀为 "TP:"，阈值为 30/80/100，只显示 CPU
+func newTestConfig() *tmux.Config {
+	return &tmux.Config{
+		PercentPrefix:    "TP:",
+		PercentSuffix:    "%",
+		Format:           "{prefix}{percent}{suffix}{icon}",
+		ColorCharging:    "green",
+		ColorHigh:        "white",
+		ColorMedium:      "yellow",
+		ColorStress:      "red",
+		StressThreshold:  30,
+		MediumThreshold:  80,
+		NotShowThreshold: 100,
+		ColorCPU:         "white",
+		ColorGPU:         "white",
+		ShowCPUInfo:      true,
+		ShowGPUInfo:      false,
+	}
+}
+
+func TestBatteryFormatETA(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		eta      time.Duration
+		charging bool
+		want     string
+	}{
+		{"默认格式", "{prefix}{percent}{suffix}{icon}", 50 * time.Hour, false, "TP:23%"},
+		{"带剩余时间", "{prefix}{percent}{suffix} ({eta})", 50 * time.Hour, false, "TP:23% (~2d)"},
+		{"剩余时间未知", "{prefix}{percent}{suffix} ({eta})", 0, false, "TP:23%"},
+		{"充电时不显示剩余时间", "{prefix}{percent}{suffix}{icon} [{eta}]", 5 * time.Hour, true, "TP:23%⚡"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newTestConfig()
+			config.Format = tt.format
+			config.ChargingIcon = "⚡"
+			config.ShowChargingIcon = true
+
+			bf := NewBatteryFormatter(config)
+			bf.SetBatteryInfo(&battery.BatteryInfo{Percentage: 23, IsCharging: tt.charging, Available: true})
+			bf.SetETA(tt.eta)
+
+			segments := bf.Segments()
+			if len(segments) != 1 {
+				t.Fatalf("应该有 1 个片段，实际 %d 个", len(segments))
+			}
+			if segments[0].Text != tt.want {
+				t.Errorf("期望 %q, 实际 %q", tt.want, segments[0].Text)
+			}
+		})
+	}
+}
+
+func TestBatteryDisconnected(t *testing.T) {
+	config := newTestConfig()
+	config.DisconnectedFormat = "{prefix}—({ago})"
+	config.ColorMuted = "colour244"
+
+	bf := NewBatteryFormatter(config)
+	bf.SetBatteryInfo(&battery.BatteryInfo{Available: false})
+	bf.SetLastSeen(&presence.Record{Name: "Magic Trackpad", Percentage: 40, LastSeen: time.Now().Add(-2*time.Hour - time.Minute)})
+
+	// 默认不显示断开的设备
+	if segments := bf.Segments(); len(segments) != 0 {
+		t.Fatalf("未启用 @tpb_show_disconnected 时不应有片段, 实际 %v", segments)
+	}
+
+	config.ShowDisconnected = true
+	segments := bf.Segments()
+	if len(segments) != 1 {
+		t.Fatalf("应该有 1 个片段，实际 %d 个", len(segments))
+	}
+	if want := "TP:—(2h ago)"; segments[0].Text != want {
+		t.Errorf("期望 %q, 实际 %q", want, segments[0].Text)
+	}
+	if segments[0].Class != ClassDisconnected {
+		t.Errorf("Class = %q", segments[0].Class)
+	}
+	if got := bf.Format(); got != "#[fg=colour244,dim]TP:—(2h ago)" {
+		t.Errorf("Format() = %q", got)
+	}
+
+	// 没有记录时不显示
+	bf.SetLastSeen(nil)
+	if segments := bf.Segments(); len(segments) != 0 {
+		t.Errorf("没有记录时不应有片段, 实际 %v", segments)
+	}
+}
+
+func TestBatteryFull(t *testing.T) {
+	config := newTestConfig()
+	config.ColorFull = "cyan"
+	config.FullIcon = "🔌"
+	config.ShowChargingIcon = true
+	config.ChargingIcon = "⚡"
+	config.NotShowThreshold = 100
+	config.ShowFull = true
+
+	full := &battery.BatteryInfo{Percentage: 100, State: battery.StateFull, Available: true}
+
+	bf := NewBatteryFormatter(config)
+	bf.SetBatteryInfo(full)
+	segments := bf.Segments()
+	if len(segments) != 1 {
+		t.Fatalf("充满并接通电源时应该显示 1 个片段，实际 %d 个", len(segments))
+	}
+	if segments[0].Class != "full" {
+		t.Errorf("Class = %q, want full", segments[0].Class)
+	}
+	if got := bf.Format(); got != "#[fg=cyan]TP:100%🔌" {
+		t.Errorf("Format() = %q", got)
+	}
+
+	// 关闭 @tpb_show_full 后恢复原来在不显示阈值处隐藏的行为
+	config.ShowFull = false
+	bf = NewBatteryFormatter(config)
+	bf.SetBatteryInfo(full)
+	if segments := bf.Segments(); len(segments) != 0 {
+		t.Errorf("关闭 @tpb_show_full 后不应显示, 实际 %v", segments)
+	}
+
+	// 没有接通电源的 100% 仍然隐藏
+	config.ShowFull = true
+	bf = NewBatteryFormatter(config)
+	bf.SetBatteryInfo(&battery.BatteryInfo{Percentage: 100, State: battery.StateDischarging, Available: true})
+	if segments := bf.Segments(); len(segments) != 0 {
+		t.Errorf("未接通电源时不应显示, 实际 %v", segments)
+	}
+}
+
+func TestBatteryDeviceOverrides(t *testing.T) {
+	prefix, stress := "M:", 40
+	mxPrefix := "MX:"
+
+	config := newTestConfig()
+	config.StressThreshold = 30
+	config.DeviceOverrides = []tmux.DeviceOverride{
+		{Key: "mouse", PercentPrefix: &prefix, StressThreshold: &stress},
+		{Key: "mx", Match: "MX Master*", PercentPrefix: &mxPrefix},
+	}
+
+	tests := []struct {
+		name  string
+		info  *battery.BatteryInfo
+		text  string
+		level string
+	}{
+		{"触摸板使用全局配置", &battery.BatteryInfo{Percentage: 35, Available: true}, "TP:35%", "medium"},
+		{"按类别覆盖", &battery.BatteryInfo{Name: "Magic Mouse", Percentage: 35, Available: true}, "M:35%", "stress"},
+		{"名称覆盖优先于类别", &battery.BatteryInfo{Name: "MX Master 3 Mouse", Percentage: 35, Available: true}, "MX:35%", "stress"},
+	}
+
+	// 同一个格式化器依次格式化不同设备，覆盖不应残留
+	bf := NewBatteryFormatter(config)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bf.SetBatteryInfo(tt.info)
+			segments := bf.Segments()
+			if len(segments) != 1 {
+				t.Fatalf("应该有 1 个片段，实际 %d 个", len(segments))
+			}
+			if segments[0].Text != tt.text {
+				t.Errorf("Text = %q, want %q", segments[0].Text, tt.text)
+			}
+			if segments[0].Class != tt.level {
+				t.Errorf("Class = %q, want %q", segments[0].Class, tt.level)
+			}
+		})
+	}
+}
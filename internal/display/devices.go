@@ -0,0 +1,139 @@
+package display
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+	"github.com/akayj/tmux-touchpad-battery/internal/tmux"
+)
+
+// FilterDevices 按 @tpb_devices 和 @tpb_exclude 筛选设备
+// shown 按 @tpb_devices 中的顺序排列，同一项匹配的多个设备保持原来的顺序；hidden 为被筛选掉的设备
+func FilterDevices(config *tmux.Config, devices []*battery.BatteryInfo) (shown, hidden []*battery.BatteryInfo) {
+	order := make(map[*battery.BatteryInfo]int)
+	for _, d := range devices {
+		i, ok := config.DeviceOrder(d.Class(), d.Name)
+		if !ok {
+			hidden = append(hidden, d)
+			continue
+		}
+		order[d] = i
+		shown = append(shown, d)
+	}
+
+	sort.SliceStable(shown, func(i, j int) bool {
+		return order[shown[i]] < order[shown[j]]
+	})
+	return shown, hidden
+}
+
+// DeviceListFormatter 按筛选后的顺序为每个设备输出一个电池片段
+// 每个设备使用独立的 BatteryFormatter，设备覆盖配置照常生效；
+// 常驻的状态栏中级别回差按设备保存在内存中，单次调用时通过 SetLevelStates 和 LevelStates 持久化
+type DeviceListFormatter struct {
+	config     *tmux.Config
+	devices    []*battery.BatteryInfo
+	formatters map[string]*BatteryFormatter
+	// shown 与 devices 一一对应的格式化器
+	shown []*BatteryFormatter
+	// restored 上一次显示的各设备级别，只用于新出现的设备
+	restored map[string]*LevelState
+}
+
+// NewDeviceListFormatter 创建新的多设备格式化器
+func NewDeviceListFormatter(config *tmux.Config) *DeviceListFormatter {
+	return &DeviceListFormatter{
+		config:     config,
+		formatters: make(map[string]*BatteryFormatter),
+	}
+}
+
+// SetDevices 设置设备列表，只保留可用且通过筛选的设备
+func (f *DeviceListFormatter) SetDevices(devices []*battery.BatteryInfo) {
+	var available []*battery.BatteryInfo
+	for _, d := range devices {
+		if d.Available {
+			available = append(available, d)
+		}
+	}
+	f.devices, _ = FilterDevices(f.config, available)
+
+	// 丢弃已经消失的设备的格式化器，标识相同的设备按出现顺序区分
+	formatters := make(map[string]*BatteryFormatter, len(f.devices))
+	f.shown = f.shown[:0]
+	for _, d := range f.devices {
+		key := deviceKey(d)
+		for n := 2; formatters[key] != nil; n++ {
+			key = deviceKey(d) + "#" + strconv.Itoa(n)
+		}
+		bf, ok := f.formatters[key]
+		if !ok {
+			bf = NewBatteryFormatter(f.config)
+			if st := f.restored[key]; st != nil {
+				bf.SetLevelState(st)
+			}
+		}
+		bf.SetBatteryInfo(d)
+		formatters[key] = bf
+		f.shown = append(f.shown, bf)
+	}
+	f.formatters = formatters
+}
+
+// SetLevelStates 设置上一次显示的各设备级别，键与 LevelStates 相同，需要在 SetDevices 之前调用
+func (f *DeviceListFormatter) SetLevelStates(states map[string]*LevelState) {
+	f.restored = states
+}
+
+// LevelStates 返回本次显示的各设备级别状态，用于持久化；没有设备启用级别回差时返回空
+func (f *DeviceListFormatter) LevelStates() map[string]*LevelState {
+	states := make(map[string]*LevelState)
+	for key, bf := range f.formatters {
+		if st := bf.LevelState(); st != nil && bf.UsesHysteresis() {
+			states[key] = st
+		}
+	}
+	return states
+}
+
+// Devices 返回筛选后的设备
+func (f *DeviceListFormatter) Devices() []*battery.BatteryInfo {
+	return f.devices
+}
+
+// Segments 依次返回每个设备的片段
+func (f *DeviceListFormatter) Segments() []Segment {
+	var segments []Segment
+	for _, bf := range f.shown {
+		segments = append(segments, bf.Segments()...)
+	}
+	return segments
+}
+
+// Format 格式化为 tmux 状态栏显示
+func (f *DeviceListFormatter) Format() string {
+	return TmuxSerializer{}.Serialize(f.Segments())
+}
+
+// Percentage 返回筛选后电量最低的设备的电量
+func (f *DeviceListFormatter) Percentage() (int, bool) {
+	lowest, ok := 0, false
+	for _, d := range f.devices {
+		if !ok || d.Percentage < lowest {
+			lowest, ok = d.Percentage, true
+		}
+	}
+	return lowest, ok
+}
+
+// deviceKey 返回设备的唯一标识，优先使用序列号
+func deviceKey(d *battery.BatteryInfo) string {
+	switch {
+	case d.Serial != "":
+		return d.Serial
+	case d.Address != "":
+		return d.Address
+	}
+	return d.Name
+}
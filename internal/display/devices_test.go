@@ -0,0 +1,119 @@
+package display
+
+import (
+	"testing"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+	"github.com/akayj/tmux-touchpad-battery/internal/tmux"
+)
+
+func TestFilterDevices(t *testing.T) {
+	trackpad := &battery.BatteryInfo{Name: "Magic Trackpad", Serial: "T1", Percentage: 80, Available: true}
+	keyboard := &battery.BatteryInfo{Name: "Magic Keyboard", Serial: "K1", Percentage: 60, Available: true}
+	mouse := &battery.BatteryInfo{Name: "Magic Mouse", Serial: "M1", Percentage: 20, Available: true}
+	airpods := &battery.BatteryInfo{Name: "AirPods Pro", Serial: "A1", Percentage: 50, Available: true}
+	devices := []*battery.BatteryInfo{mouse, airpods, keyboard, trackpad}
+
+	tests := []struct {
+		name          string
+		include       []string
+		exclude       []string
+		shown, hidden []*battery.BatteryInfo
+	}{
+		{"不筛选", nil, nil, devices, nil},
+		{"按类别排序", []string{"trackpad", "keyboard"}, nil, []*battery.BatteryInfo{trackpad, keyboard}, []*battery.BatteryInfo{mouse, airpods}},
+		{"排除名称", nil, []string{"AirPods*"}, []*battery.BatteryInfo{mouse, keyboard, trackpad}, []*battery.BatteryInfo{airpods}},
+		{"通配其余设备", []string{"keyboard", "*"}, []string{"airpods*"}, []*battery.BatteryInfo{keyboard, mouse, trackpad}, []*battery.BatteryInfo{airpods}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newTestConfig()
+			config.Devices = tt.include
+			config.ExcludeDevices = tt.exclude
+
+			shown, hidden := FilterDevices(config, devices)
+			if !sameDevices(shown, tt.shown) {
+				t.Errorf("shown = %v, want %v", names(shown), names(tt.shown))
+			}
+			if !sameDevices(hidden, tt.hidden) {
+				t.Errorf("hidden = %v, want %v", names(hidden), names(tt.hidden))
+			}
+		})
+	}
+}
+
+func TestDeviceListFormatter(t *testing.T) {
+	config := newTestConfig()
+	config.Devices = []string{"trackpad", "mouse"}
+	config.StressThreshold = 30
+
+	f := NewDeviceListFormatter(config)
+	f.SetDevices([]*battery.BatteryInfo{
+		{Name: "Magic Mouse", Serial: "M1", Percentage: 20, Available: true},
+		{Name: "Magic Keyboard", Serial: "K1", Percentage: 60, Available: true},
+		{Name: "Magic Trackpad", Serial: "T1", Percentage: 80, Available: false},
+		{Name: "Magic Trackpad", Serial: "T2", Percentage: 50, Available: true},
+	})
+
+	segments := f.Segments()
+	if len(segments) != 2 {
+		t.Fatalf("应该有 2 个片段，实际 %d 个: %v", len(segments), segments)
+	}
+	if segments[0].Text != "TP:50%" || segments[1].Text != "TP:20%" {
+		t.Errorf("片段顺序错误: %q, %q", segments[0].Text, segments[1].Text)
+	}
+	if segments[1].Class != "stress" {
+		t.Errorf("鼠标的级别 = %q, want stress", segments[1].Class)
+	}
+	if percent, ok := f.Percentage(); !ok || percent != 20 {
+		t.Errorf("Percentage() = %d, %v", percent, ok)
+	}
+}
+
+func TestDeviceListFormatterDefaultPrefix(t *testing.T) {
+	config := newTestConfig()
+	config.PercentPrefix = tmux.DefaultPercentPrefix
+	config.Devices = []string{"trackpad", "mouse", "keyboard"}
+
+	f := NewDeviceListFormatter(config)
+	f.SetDevices([]*battery.BatteryInfo{
+		{Name: "Magic Trackpad", Percentage: 50, Available: true},
+		{Name: "MX Master 3", Kind: battery.ClassMouse, Percentage: 85, Available: true},
+		{Kind: battery.ClassKeyboard, Percentage: 64, Available: true},
+	})
+
+	// 非触摸板设备使用设备名称，没有名称时使用类别
+	segments := f.Segments()
+	want := []string{"Touchpad:50%", "MX Master 3:85%", "keyboard:64%"}
+	if len(segments) != len(want) {
+		t.Fatalf("应该有 %d 个片段，实际 %d 个: %v", len(want), len(segments), segments)
+	}
+	for i, w := range want {
+		if segments[i].Text != w {
+			t.Errorf("片段 %d = %q, 期望 %q", i, segments[i].Text, w)
+		}
+	}
+}
+
+// sameDevices 判断两个设备列表是否按顺序相同
+func sameDevices(a, b []*battery.BatteryInfo) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// names 返回设备名称列表，用于错误信息
+func names(devices []*battery.BatteryInfo) []string {
+	var result []string
+	for _, d := range devices {
+		result = append(result, d.Name)
+	}
+	return result
+}
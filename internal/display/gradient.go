@@ -0,0 +1,98 @@
+package display
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/color"
+)
+
+// 颜色模式
+const (
+	ColorModeThreshold = "threshold"
+	ColorModeGradient  = "gradient"
+)
+
+// GradientStop 表示渐变中的一个颜色节点
+type GradientStop struct {
+	Percent int
+	Color   color.RGB
+}
+
+// ParseGradientStops 解析渐变节点配置，例如 "red@10,yellow@50,#00ff00@90"
+func ParseGradientStops(spec string) ([]GradientStop, error) {
+	var stops []GradientStop
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		at := strings.LastIndex(part, "@")
+		if at <= 0 || at == len(part)-1 {
+			return nil, fmt.Errorf("无效的渐变节点: %q", part)
+		}
+
+		percent, err := strconv.Atoi(strings.TrimSpace(part[at+1:]))
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("无效的渐变百分比: %q", part)
+		}
+
+		c, err := color.Parse(part[:at])
+		if err != nil {
+			return nil, err
+		}
+
+		// 默认颜色没有确定的 RGB 值，无法参与插值
+		value, ok := c.ToRGB()
+		if !ok {
+			return nil, fmt.Errorf("渐变颜色不能是默认颜色: %q", part)
+		}
+
+		stops = append(stops, GradientStop{Percent: percent, Color: value})
+	}
+
+	if len(stops) < 2 {
+		return nil, fmt.Errorf("渐变至少需要两个节点: %q", spec)
+	}
+
+	sort.SliceStable(stops, func(i, j int) bool {
+		return stops[i].Percent < stops[j].Percent
+	})
+
+	return stops, nil
+}
+
+// gradientAt 计算指定百分比在渐变中的颜色
+func gradientAt(stops []GradientStop, percent int) color.RGB {
+	if percent <= stops[0].Percent {
+		return stops[0].Color
+	}
+
+	for i := 1; i < len(stops); i++ {
+		lo, hi := stops[i-1], stops[i]
+		if percent > hi.Percent {
+			continue
+		}
+		if hi.Percent == lo.Percent {
+			return hi.Color
+		}
+
+		t := float64(percent-lo.Percent) / float64(hi.Percent-lo.Percent)
+		return color.RGB{
+			R: lerp(lo.Color.R, hi.Color.R, t),
+			G: lerp(lo.Color.G, hi.Color.G, t),
+			B: lerp(lo.Color.B, hi.Color.B, t),
+		}
+	}
+
+	return stops[len(stops)-1].Color
+}
+
+// lerp 在两个通道值之间线性插值
+func lerp(a, b uint8, t float64) uint8 {
+	return uint8(float64(a) + (float64(b)-float64(a))*t + 0.5)
+}
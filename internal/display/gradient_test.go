@@ -0,0 +1,53 @@
+package display
+
+import (
+	"testing"
+)
+
+func TestParseGradientStops(t *testing.T) {
+	stops, err := ParseGradientStops("green@90, red@10,#ffff00@50")
+	if err != nil {
+		t.Fatalf("解析渐变节点失败: %v", err)
+	}
+
+	if len(stops) != 3 {
+		t.Fatalf("应该解析出 3 个节点，实际: %d", len(stops))
+	}
+
+	// 节点应按百分比排序
+	if stops[0].Percent != 10 || stops[1].Percent != 50 || stops[2].Percent != 90 {
+		t.Errorf("节点顺序错误: %+v", stops)
+	}
+
+	for _, spec := range []string{"red@10", "red@x,green@90", "nocolor@10,green@90", "red@10,green@101", "default@10,green@90"} {
+		if _, err := ParseGradientStops(spec); err == nil {
+			t.Errorf("无效配置 %q 应该返回错误", spec)
+		}
+	}
+}
+
+func TestGradientAt(t *testing.T) {
+	stops, err := ParseGradientStops("#ff0000@10,#ffff00@50,#00ff00@90")
+	if err != nil {
+		t.Fatalf("解析渐变节点失败: %v", err)
+	}
+
+	tests := []struct {
+		percent int
+		want    string
+	}{
+		{0, "#ff0000"},
+		{10, "#ff0000"},
+		{30, "#ff8000"},
+		{50, "#ffff00"},
+		{70, "#80ff00"},
+		{90, "#00ff00"},
+		{100, "#00ff00"},
+	}
+
+	for _, tt := range tests {
+		if got := gradientAt(stops, tt.percent).Hex(); got != tt.want {
+			t.Errorf("gradientAt(%d) = %s，期望 %s", tt.percent, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package display
+
+import (
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+)
+
+// LevelState 上一次显示的电量级别，用于在阈值附近保持颜色和闪烁状态稳定
+type LevelState struct {
+	Level Level `json:"level"`
+	// Pending 与 Level 不同的原始级别，以及它从何时开始持续
+	Pending      Level     `json:"pending"`
+	PendingSince time.Time `json:"pending_since,omitempty"`
+}
+
+// levelRank 非充电级别从低电量到隐藏的顺序
+var levelRank = map[Level]int{
+	LevelStress: 0,
+	LevelMedium: 1,
+	LevelHigh:   2,
+	LevelHidden: 3,
+}
+
+// rankLevels 按顺序排列的非充电级别
+var rankLevels = []Level{LevelStress, LevelMedium, LevelHigh, LevelHidden}
+
+// ranked 判断级别是否参与回差（充电和充满不参与）
+func ranked(l Level) bool {
+	_, ok := levelRank[l]
+	return ok
+}
+
+// UsesHysteresis 判断是否启用了级别回差
+func (f *BatteryFormatter) UsesHysteresis() bool {
+	return f.config.LevelHysteresis > 0 || f.config.LevelMinDuration > 0
+}
+
+// SetLevelState 设置上一次显示的级别，需要在 SetBatteryInfo 之前调用
+func (f *BatteryFormatter) SetLevelState(st *LevelState) {
+	f.levelState = st
+}
+
+// LevelState 返回本次显示的级别状态，用于持久化；没有电池信息时返回 nil
+func (f *BatteryFormatter) LevelState() *LevelState {
+	return f.levelState
+}
+
+// applyHysteresis 根据上一次的级别计算本次显示的级别
+func (f *BatteryFormatter) applyHysteresis(info *battery.BatteryInfo) {
+	f.levelInfo = nil
+	if info == nil || !info.Available || !f.UsesHysteresis() {
+		return
+	}
+
+	now := time.Now()
+	if f.now != nil {
+		now = f.now()
+	}
+
+	next := nextLevelState(f.levelState, f.rawLevel(info), info.Percentage, now, f.thresholds(),
+		f.config.LevelHysteresis, time.Duration(f.config.LevelMinDuration)*time.Second)
+	f.levelState = &next
+	f.levelInfo = info
+}
+
+// thresholds 相邻级别之间的阈值，依次为 stress/medium、medium/high、high/hidden
+func (f *BatteryFormatter) thresholds() []int {
+	return []int{f.config.StressThreshold, f.config.MediumThreshold, f.config.NotShowThreshold}
+}
+
+// nextLevelState 计算新的级别状态
+// 充电状态（包括充满）变化和电量下降时立即切换；电量回升时需要超过阈值 hysteresis 个百分点，
+// 或者新级别持续 minDuration（大于 0 时）之后才切换
+func nextLevelState(prev *LevelState, raw Level, percent int, now time.Time, thresholds []int, hysteresis int, minDuration time.Duration) LevelState {
+	if prev == nil || raw == prev.Level || !ranked(raw) || !ranked(prev.Level) {
+		return LevelState{Level: raw}
+	}
+
+	from, to := levelRank[prev.Level], levelRank[raw]
+	if to < from {
+		return LevelState{Level: raw}
+	}
+
+	// 逐级检查是否超过回差
+	level := prev.Level
+	for r := from + 1; r <= to; r++ {
+		if percent < thresholds[r-1]+hysteresis {
+			break
+		}
+		level = rankLevels[r]
+	}
+	if level == raw {
+		return LevelState{Level: raw}
+	}
+
+	st := LevelState{Level: level, Pending: raw, PendingSince: now}
+	if prev.Pending == raw && !prev.PendingSince.IsZero() {
+		st.PendingSince = prev.PendingSince
+	}
+	if minDuration > 0 && now.Sub(st.PendingSince) >= minDuration {
+		return LevelState{Level: raw}
+	}
+	return st
+}
@@ -0,0 +1,136 @@
+package display
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+)
+
+func TestNextLevelState(t *testing.T) {
+	thresholds := []int{30, 80, 100}
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		prev    *LevelState
+		raw     Level
+		percent int
+		want    Level
+	}{
+		{"没有上一次状态", nil, LevelMedium, 30, LevelMedium},
+		{"电量下降立即切换", &LevelState{Level: LevelMedium}, LevelStress, 29, LevelStress},
+		{"回升未超过回差", &LevelState{Level: LevelStress}, LevelMedium, 31, LevelStress},
+		{"回升超过回差", &LevelState{Level: LevelStress}, LevelMedium, 32, LevelMedium},
+		{"跨多个级别时逐级检查", &LevelState{Level: LevelStress}, LevelHigh, 81, LevelMedium},
+		{"开始充电立即切换", &LevelState{Level: LevelStress}, LevelCharging, 30, LevelCharging},
+		{"停止充电立即切换", &LevelState{Level: LevelCharging}, LevelMedium, 30, LevelMedium},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nextLevelState(tt.prev, tt.raw, tt.percent, now, thresholds, 2, 0)
+			if got.Level != tt.want {
+				t.Errorf("Level = %v, want %v", got.Level, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextLevelStateMinDuration(t *testing.T) {
+	thresholds := []int{30, 80, 100}
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	st := LevelState{Level: LevelStress}
+	st = nextLevelState(&st, LevelMedium, 30, now, thresholds, 2, 5*time.Minute)
+	if st.Level != LevelStress || st.Pending != LevelMedium {
+		t.Fatalf("st = %+v", st)
+	}
+
+	// 持续时间未到，保持原级别且不重置开始时间
+	st = nextLevelState(&st, LevelMedium, 31, now.Add(3*time.Minute), thresholds, 2, 5*time.Minute)
+	if st.Level != LevelStress || !st.PendingSince.Equal(now) {
+		t.Fatalf("st = %+v", st)
+	}
+
+	st = nextLevelState(&st, LevelMedium, 30, now.Add(5*time.Minute), thresholds, 2, 5*time.Minute)
+	if st.Level != LevelMedium {
+		t.Errorf("持续 5 分钟后应切换, st = %+v", st)
+	}
+}
+
+func TestBatteryHysteresisNoFlicker(t *testing.T) {
+	config := newTestConfig()
+	config.StressThreshold = 30
+	config.MediumThreshold = 80
+	config.ColorStress = "red"
+	config.ColorMedium = "yellow"
+	config.BlinkOnLowBattery = true
+	config.LevelHysteresis = 2
+
+	bf := NewBatteryFormatter(config)
+
+	// 在 29% 和 30% 之间来回变化时保持低电量颜色和闪烁
+	for i, percent := range []int{29, 30, 29, 30, 31} {
+		bf.SetBatteryInfo(&battery.BatteryInfo{Percentage: percent, Available: true})
+		segments := bf.Segments()
+		if len(segments) != 1 || segments[0].Class != LevelStress.String() {
+			t.Fatalf("第 %d 次 (%d%%): segments = %+v", i, percent, segments)
+		}
+		if got := bf.Format(); got != "#[fg=red,blink]TP:"+strconv.Itoa(percent)+"%" {
+			t.Errorf("第 %d 次 (%d%%): Format() = %q", i, percent, got)
+		}
+	}
+
+	// 回升到阈值加回差后切换
+	bf.SetBatteryInfo(&battery.BatteryInfo{Percentage: 32, Available: true})
+	if got := bf.Format(); got != "#[fg=yellow]TP:32%" {
+		t.Errorf("Format() = %q", got)
+	}
+
+	// 状态可以交给新的格式化器，模拟每次重绘都重新启动的 tmux 调用
+	next := NewBatteryFormatter(config)
+	next.SetLevelState(bf.LevelState())
+	next.SetBatteryInfo(&battery.BatteryInfo{Percentage: 31, Available: true})
+	if got := next.Format(); got != "#[fg=yellow]TP:31%" {
+		t.Errorf("恢复状态后 Format() = %q", got)
+	}
+}
+
+func TestDeviceListLevelStates(t *testing.T) {
+	config := newTestConfig()
+	config.Devices = []string{"mouse", "keyboard"}
+	config.StressThreshold = 30
+	config.MediumThreshold = 80
+	config.LevelHysteresis = 2
+
+	devices := []*battery.BatteryInfo{
+		{Name: "Magic Mouse", Serial: "M1", Percentage: 29, Available: true},
+		{Name: "Magic Keyboard", Serial: "K1", Percentage: 60, Available: true},
+	}
+	list := NewDeviceListFormatter(config)
+	list.SetDevices(devices)
+
+	states := list.LevelStates()
+	if len(states) != 2 || states["M1"] == nil || states["M1"].Level != LevelStress {
+		t.Fatalf("LevelStates() = %+v", states)
+	}
+
+	// 状态按设备交给新的格式化器，鼠标回升到 31% 时保持低电量级别
+	next := NewDeviceListFormatter(config)
+	next.SetLevelStates(states)
+	devices[0] = &battery.BatteryInfo{Name: "Magic Mouse", Serial: "M1", Percentage: 31, Available: true}
+	next.SetDevices(devices)
+	if segments := next.Segments(); len(segments) != 2 || segments[0].Class != LevelStress.String() {
+		t.Errorf("恢复状态后 segments = %+v", segments)
+	}
+
+	// 没有启用级别回差时不保存
+	config.LevelHysteresis = 0
+	off := NewDeviceListFormatter(config)
+	off.SetDevices(devices)
+	if states := off.LevelStates(); len(states) != 0 {
+		t.Errorf("未启用级别回差时 LevelStates() = %+v", states)
+	}
+}
@@ -0,0 +1,122 @@
+package display
+
+import (
+	"strings"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/color"
+	"github.com/akayj/tmux-touchpad-battery/internal/style"
+	"github.com/akayj/tmux-touchpad-battery/internal/tmux"
+)
+
+// Powerline 所在的状态栏位置
+const (
+	PowerlineSideLeft  = "left"
+	PowerlineSideRight = "right"
+)
+
+// PowerlineFormatter 将多个片段渲染为带背景色和分隔符的 powerline 色块
+type PowerlineFormatter struct {
+	config  *tmux.Config
+	sources []SegmentFormatter
+}
+
+// NewPowerlineFormatter 创建新的 powerline 格式化器，片段按 sources 的顺序排列
+func NewPowerlineFormatter(config *tmux.Config, sources ...SegmentFormatter) *PowerlineFormatter {
+	return &PowerlineFormatter{
+		config:  config,
+		sources: sources,
+	}
+}
+
+// Segments 返回带色块背景的片段
+func (f *PowerlineFormatter) Segments() []Segment {
+	var segments []Segment
+	for _, source := range f.sources {
+		for _, seg := range source.Segments() {
+			seg.Style = f.blockStyle(seg)
+			segments = append(segments, seg)
+		}
+	}
+	return segments
+}
+
+// Format 格式化为 tmux 状态栏显示
+func (f *PowerlineFormatter) Format() string {
+	return f.render(func(st style.Style, text string) string {
+		return st.Tmux() + text
+	}, "#[default]")
+}
+
+// FormatWithStyle 使用 lipgloss 格式化（用于终端显示）
+func (f *PowerlineFormatter) FormatWithStyle() string {
+	return f.render(func(st style.Style, text string) string {
+		return st.Lipgloss().Render(text)
+	}, "")
+}
+
+// render 按所在位置拼接色块和分隔符
+// 右侧状态栏使用指向左边的分隔符，颜色从前一个色块（或状态栏背景）过渡到当前色块；
+// 左侧状态栏使用指向右边的分隔符，颜色从当前色块过渡到后一个色块
+func (f *PowerlineFormatter) render(paint func(st style.Style, text string) string, reset string) string {
+	segments := f.Segments()
+	if len(segments) == 0 {
+		return ""
+	}
+
+	statusBg := color.MustParse(f.config.PowerlineStatusBg)
+	separator := func(fg, bg *color.Color) style.Style {
+		return style.Style{Fg: fg, Bg: bg, ClearAttrs: true}
+	}
+
+	var b strings.Builder
+
+	if f.config.PowerlineSide == PowerlineSideLeft {
+		for i, seg := range segments {
+			next := &statusBg
+			if i+1 < len(segments) {
+				next = segments[i+1].Style.Bg
+			}
+			b.WriteString(paint(seg.Style, " "+seg.Text+" "))
+			b.WriteString(paint(separator(seg.Style.Bg, next), f.config.PowerlineRightSeparator))
+		}
+	} else {
+		prev := &statusBg
+		for _, seg := range segments {
+			b.WriteString(paint(separator(seg.Style.Bg, prev), f.config.PowerlineLeftSeparator))
+			b.WriteString(paint(seg.Style, " "+seg.Text+" "))
+			prev = seg.Style.Bg
+		}
+	}
+
+	b.WriteString(reset)
+	return b.String()
+}
+
+// blockStyle 计算片段的色块样式
+// 片段自带背景色时保持不变；CPU/GPU（含 CPU 迷你图）使用配置的背景色；
+// 其余片段（电池）将前景色作为色块背景，文字使用 powerline 前景色
+func (f *PowerlineFormatter) blockStyle(seg Segment) style.Style {
+	st := seg.Style
+	if st.Bg != nil {
+		return st
+	}
+
+	var bg string
+	switch seg.Name {
+	case SegmentCPU, SegmentCPUSparkline:
+		bg = f.config.PowerlineCPUBg
+	case SegmentGPU:
+		bg = f.config.PowerlineGPUBg
+	}
+
+	if bg != "" {
+		return st.WithBg(color.MustParse(bg))
+	}
+
+	if st.Fg != nil {
+		st = st.WithBg(*st.Fg)
+	} else {
+		st = st.WithBg(color.MustParse(f.config.PowerlineStatusBg))
+	}
+	return st.WithFg(color.MustParse(f.config.PowerlineFg))
+}
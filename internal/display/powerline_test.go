@@ -0,0 +1,74 @@
+package display
+
+import (
+	"testing"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+	"github.com/akayj/tmux-touchpad-battery/internal/system"
+	"github.com/akayj/tmux-touchpad-battery/internal/tmux"
+)
+
+// newPowerlineTestConfig 在 newTestConfig 的基础上启用 powerline
+func newPowerlineTestConfig(side string) *tmux.Config {
+	config := newTestConfig()
+	config.Powerline = true
+	config.PowerlineSide = side
+	config.PowerlineLeftSeparator = "<"
+	config.PowerlineRightSeparator = ">"
+	config.PowerlineStatusBg = "colour235"
+	config.PowerlineFg = "black"
+	config.PowerlineCPUBg = "colour238"
+	config.PowerlineGPUBg = "colour236"
+	return config
+}
+
+func TestPowerlineFormatRight(t *testing.T) {
+	config := newPowerlineTestConfig(PowerlineSideRight)
+
+	bf := NewBatteryFormatter(config)
+	bf.SetBatteryInfo(&battery.BatteryInfo{Percentage: 50, Available: true})
+	sf := NewSystemFormatter(config)
+	sf.SetSystemInfo(&system.SystemInfo{CPUUsage: 12.5, Available: true})
+
+	got := NewPowerlineFormatter(config, bf, sf).Format()
+	want := "#[fg=yellow,bg=colour235,none]<" +
+		"#[fg=black,bg=yellow] TP:50% " +
+		"#[fg=colour238,bg=yellow,none]<" +
+		"#[fg=white,bg=colour238] CPU:12.5% " +
+		"#[default]"
+
+	if got != want {
+		t.Errorf("Format() =\n%q\n期望\n%q", got, want)
+	}
+}
+
+func TestPowerlineFormatLeft(t *testing.T) {
+	config := newPowerlineTestConfig(PowerlineSideLeft)
+
+	bf := NewBatteryFormatter(config)
+	bf.SetBatteryInfo(&battery.BatteryInfo{Percentage: 50, Available: true})
+	sf := NewSystemFormatter(config)
+	sf.SetSystemInfo(&system.SystemInfo{CPUUsage: 12.5, Available: true})
+
+	got := NewPowerlineFormatter(config, bf, sf).Format()
+	want := "#[fg=black,bg=yellow] TP:50% " +
+		"#[fg=yellow,bg=colour238,none]>" +
+		"#[fg=white,bg=colour238] CPU:12.5% " +
+		"#[fg=colour238,bg=colour235,none]>" +
+		"#[default]"
+
+	if got != want {
+		t.Errorf("Format() =\n%q\n期望\n%q", got, want)
+	}
+}
+
+func TestPowerlineEmpty(t *testing.T) {
+	config := newPowerlineTestConfig(PowerlineSideRight)
+
+	bf := NewBatteryFormatter(config)
+	bf.SetBatteryInfo(&battery.BatteryInfo{Available: false})
+
+	if got := NewPowerlineFormatter(config, bf).Format(); got != "" {
+		t.Errorf("没有片段时应该输出空字符串，实际: %q", got)
+	}
+}
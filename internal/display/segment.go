@@ -0,0 +1,31 @@
+package display
+
+import (
+	"github.com/akayj/tmux-touchpad-battery/internal/style"
+)
+
+// 片段名称
+const (
+	SegmentBattery = "battery"
+	SegmentCPU     = "cpu"
+	SegmentGPU     = "gpu"
+
+	SegmentBatterySparkline = "battery_sparkline"
+	SegmentCPUSparkline     = "cpu_sparkline"
+)
+
+// Segment 表示一个带样式的显示片段
+type Segment struct {
+	Name  string
+	Text  string
+	Style style.Style
+
+	// Class 片段的语义类别（如电量级别），供外部状态栏做样式匹配
+	Class string
+}
+
+// SegmentFormatter 定义了可以输出样式片段的格式化器
+type SegmentFormatter interface {
+	// Segments 返回当前信息对应的样式片段，无内容时返回 nil
+	Segments() []Segment
+}
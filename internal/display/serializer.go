@@ -0,0 +1,239 @@
+package display
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/color"
+	"github.com/akayj/tmux-touchpad-battery/internal/style"
+)
+
+// Serializer 将样式片段序列化为特定目标（tmux、shell 提示符、状态栏）的标记
+type Serializer interface {
+	// Serialize 序列化片段，片段之间以空格分隔
+	Serialize(segments []Segment) string
+}
+
+// SerializerFor 按名称返回序列化器
+func SerializerFor(name string) (Serializer, error) {
+	switch name {
+	case "tmux":
+		return TmuxSerializer{}, nil
+	case "zsh":
+		return ZshSerializer{}, nil
+	case "bash":
+		return BashSerializer{}, nil
+	case "fish":
+		return FishSerializer{}, nil
+	case "plain", "starship":
+		return PlainSerializer{}, nil
+	case "polybar":
+		return PolybarSerializer{}, nil
+	}
+	return nil, fmt.Errorf("不支持的输出目标: %q", name)
+}
+
+// TmuxSerializer 输出 tmux 样式标记
+type TmuxSerializer struct{}
+
+// Serialize 实现 Serializer，相邻片段样式相同时只输出一次样式标记
+func (TmuxSerializer) Serialize(segments []Segment) string {
+	var b strings.Builder
+	prev := ""
+	for i, seg := range segments {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		if markup := seg.Style.Tmux(); markup != prev {
+			b.WriteString(markup)
+			prev = markup
+		}
+		b.WriteString(seg.Text)
+	}
+	return b.String()
+}
+
+// PlainSerializer 输出不带任何样式的纯文本，适用于 Starship 自定义模块
+type PlainSerializer struct{}
+
+// Serialize 实现 Serializer
+func (PlainSerializer) Serialize(segments []Segment) string {
+	texts := make([]string, 0, len(segments))
+	for _, seg := range segments {
+		texts = append(texts, seg.Text)
+	}
+	return strings.Join(texts, " ")
+}
+
+// ZshSerializer 输出 zsh 提示符转义（%F{red}...%f）
+type ZshSerializer struct{}
+
+// Serialize 实现 Serializer
+// zsh 原生支持粗体、下划线和反色，其余属性以 %{...%} 包裹的 SGR 序列输出
+func (ZshSerializer) Serialize(segments []Segment) string {
+	parts := make([]string, 0, len(segments))
+	for _, seg := range segments {
+		st := seg.Style
+		var open, close string
+
+		if st.Attrs&style.AttrBold != 0 {
+			open, close = open+"%B", "%b"+close
+		}
+		if st.Attrs&style.AttrUnderscore != 0 {
+			open, close = open+"%U", "%u"+close
+		}
+		if st.Attrs&style.AttrReverse != 0 {
+			open, close = open+"%S", "%s"+close
+		}
+		if st.Fg != nil && !st.Fg.IsDefault() {
+			open, close = open+"%F{"+zshColor(*st.Fg)+"}", "%f"+close
+		}
+		if st.Bg != nil && !st.Bg.IsDefault() {
+			open, close = open+"%K{"+zshColor(*st.Bg)+"}", "%k"+close
+		}
+
+		extra := style.Style{Attrs: st.Attrs &^ (style.AttrBold | style.AttrUnderscore | style.AttrReverse)}
+		if sgr := extra.SGR(); sgr != "" {
+			open, close = open+"%{"+sgr+"%}", "%{\x1b[0m%}"+close
+		}
+
+		parts = append(parts, open+strings.ReplaceAll(seg.Text, "%", "%%")+close)
+	}
+	return strings.Join(parts, " ")
+}
+
+// zshColor 返回 zsh 的颜色参数，亮色和 256 色使用编号
+func zshColor(c color.Color) string {
+	switch c.Kind {
+	case color.KindANSI:
+		if c.Index < 8 {
+			return c.String()
+		}
+		return strconv.Itoa(c.Index)
+	case color.KindIndexed:
+		return strconv.Itoa(c.Index)
+	}
+	return c.String()
+}
+
+// BashSerializer 输出 bash PS1 转义（\[\e[31m\]...\[\e[0m\]）
+type BashSerializer struct{}
+
+// bashEscaper 转义 PS1 中会被 bash 解释的字符
+var bashEscaper = strings.NewReplacer(`\`, `\\`, "$", `\$`, "`", "\\`")
+
+// Serialize 实现 Serializer
+func (BashSerializer) Serialize(segments []Segment) string {
+	parts := make([]string, 0, len(segments))
+	for _, seg := range segments {
+		text := bashEscaper.Replace(seg.Text)
+		if sgr := seg.Style.SGR(); sgr != "" {
+			text = `\[` + strings.ReplaceAll(sgr, "\x1b", `\e`) + `\]` + text + `\[\e[0m\]`
+		}
+		parts = append(parts, text)
+	}
+	return strings.Join(parts, " ")
+}
+
+// FishSerializer 输出 fish 脚本（set_color 与 printf），用于 `| source`
+type FishSerializer struct{}
+
+// fishEscaper 转义 fish 单引号字符串
+var fishEscaper = strings.NewReplacer(`\`, `\\`, "'", `\'`)
+
+// Serialize 实现 Serializer
+func (FishSerializer) Serialize(segments []Segment) string {
+	var lines []string
+	for i, seg := range segments {
+		if i > 0 {
+			lines = append(lines, "printf ' '")
+		}
+
+		args := fishColorArgs(seg.Style)
+		if len(args) > 0 {
+			lines = append(lines, "set_color "+strings.Join(args, " "))
+		}
+		lines = append(lines, "printf '%s' '"+fishEscaper.Replace(seg.Text)+"'")
+		if len(args) > 0 {
+			lines = append(lines, "set_color normal")
+		}
+	}
+	return strings.Join(lines, "; ")
+}
+
+// fishColorArgs 返回 set_color 的参数
+func fishColorArgs(st style.Style) []string {
+	var args []string
+
+	if st.Fg != nil && !st.Fg.IsDefault() {
+		args = append(args, fishColor(*st.Fg))
+	}
+	if st.Bg != nil && !st.Bg.IsDefault() {
+		args = append(args, "--background="+fishColor(*st.Bg))
+	}
+
+	for _, a := range []struct {
+		attr style.Attr
+		flag string
+	}{
+		{style.AttrBold, "--bold"},
+		{style.AttrDim, "--dim"},
+		{style.AttrItalics, "--italics"},
+		{style.AttrReverse, "--reverse"},
+		{style.AttrUnderscore, "--underline"},
+	} {
+		if st.Attrs&a.attr != 0 {
+			args = append(args, a.flag)
+		}
+	}
+
+	return args
+}
+
+// fishColor 返回 fish 的颜色名，fish 不支持 256 色编号，转换为十六进制
+func fishColor(c color.Color) string {
+	if c.Kind == color.KindANSI {
+		if c.Index < 8 {
+			return c.String()
+		}
+		return "br" + strings.TrimPrefix(c.String(), "bright")
+	}
+	rgb, _ := c.ToRGB()
+	return strings.TrimPrefix(rgb.Hex(), "#")
+}
+
+// PolybarSerializer 输出 Polybar 格式标签
+type PolybarSerializer struct{}
+
+// Serialize 实现 Serializer
+func (PolybarSerializer) Serialize(segments []Segment) string {
+	parts := make([]string, 0, len(segments))
+	for _, seg := range segments {
+		parts = append(parts, polybarSegment(seg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// polybarSegment 将一个片段包裹在 Polybar 格式标签中
+func polybarSegment(seg Segment) string {
+	text := seg.Text
+	st := seg.Style
+
+	if st.Attrs&style.AttrReverse != 0 {
+		text = "%{R}" + text + "%{R}"
+	}
+	if us := hexColor(st.Us); us != "" {
+		text = "%{u" + us + "}%{+u}" + text + "%{-u}%{u-}"
+	} else if st.Attrs&style.AttrUnderscore != 0 {
+		text = "%{+u}" + text + "%{-u}"
+	}
+	if bg := hexColor(st.Bg); bg != "" {
+		text = "%{B" + bg + "}" + text + "%{B-}"
+	}
+	if fg := hexColor(st.Fg); fg != "" {
+		text = "%{F" + fg + "}" + text + "%{F-}"
+	}
+
+	return text
+}
@@ -0,0 +1,53 @@
+package display
+
+import (
+	"testing"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/style"
+)
+
+func TestSerializers(t *testing.T) {
+	stress, err := style.Parse("fg=red,bold,blink")
+	if err != nil {
+		t.Fatalf("解析样式失败: %v", err)
+	}
+	cpu, err := style.Parse("fg=colour214")
+	if err != nil {
+		t.Fatalf("解析样式失败: %v", err)
+	}
+
+	segments := []Segment{
+		{Name: SegmentBattery, Text: "TP:20%", Style: stress},
+		{Name: SegmentCPU, Text: "CPU:$1", Style: cpu},
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"tmux", "#[fg=red,bold,blink]TP:20% #[fg=colour214]CPU:$1"},
+		{"plain", "TP:20% CPU:$1"},
+		{"starship", "TP:20% CPU:$1"},
+		{"zsh", "%B%F{red}%{\x1b[5m%}TP:20%%%{\x1b[0m%}%f%b %F{214}CPU:$1%f"},
+		{"bash", `\[\e[1;5;31m\]TP:20%\[\e[0m\] \[\e[38;5;214m\]CPU:\$1\[\e[0m\]`},
+		{"fish", "set_color red --bold; printf '%s' 'TP:20%'; set_color normal; printf ' '; " +
+			"set_color ffaf00; printf '%s' 'CPU:$1'; set_color normal"},
+		{"polybar", "%{F#cd0000}TP:20%%{F-} %{F#ffaf00}CPU:$1%{F-}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serializer, err := SerializerFor(tt.name)
+			if err != nil {
+				t.Fatalf("获取序列化器失败: %v", err)
+			}
+			if got := serializer.Serialize(segments); got != tt.want {
+				t.Errorf("Serialize() =\n%q\n期望\n%q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := SerializerFor("powershell"); err == nil {
+		t.Error("不支持的目标应该返回错误")
+	}
+}
@@ -0,0 +1,85 @@
+package display
+
+import (
+	"github.com/akayj/tmux-touchpad-battery/internal/color"
+	"github.com/akayj/tmux-touchpad-battery/internal/style"
+	"github.com/akayj/tmux-touchpad-battery/internal/tmux"
+)
+
+// 迷你图数据来源
+const (
+	SparklineBattery = "battery"
+	SparklineCPU     = "cpu"
+)
+
+// sparkBars 迷你图使用的 8 级方块字符
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline 将 0-100 的百分比序列渲染为 Unicode 迷你图
+// 使用固定的 0-100 刻度，不同时间的迷你图高度可以直接比较
+func Sparkline(values []int) string {
+	runes := make([]rune, 0, len(values))
+	for _, v := range values {
+		v = min(max(v, 0), 100)
+		idx := (v*(len(sparkBars)-1) + 50) / 100
+		runes = append(runes, sparkBars[idx])
+	}
+	return string(runes)
+}
+
+// SparklineFormatter 将电池电量或 CPU 使用率的最近记录格式化为迷你图
+type SparklineFormatter struct {
+	config *tmux.Config
+	source string
+	values []int
+}
+
+// NewSparklineFormatter 创建新的迷你图格式化器，source 为 SparklineBattery 或 SparklineCPU
+func NewSparklineFormatter(config *tmux.Config, source string) *SparklineFormatter {
+	return &SparklineFormatter{
+		config: config,
+		source: source,
+	}
+}
+
+// SetValues 设置按时间从旧到新排列的记录，只保留最近 @tpb_sparkline_length 条
+func (f *SparklineFormatter) SetValues(values []int) {
+	if n := f.config.SparklineLength; n > 0 && len(values) > n {
+		values = values[len(values)-n:]
+	}
+	f.values = values
+}
+
+// Format 格式化迷你图为 tmux 状态栏显示
+func (f *SparklineFormatter) Format() string {
+	return TmuxSerializer{}.Serialize(f.Segments())
+}
+
+// Segments 返回迷你图片段，记录不足两条时不显示
+func (f *SparklineFormatter) Segments() []Segment {
+	if len(f.values) < 2 {
+		return nil
+	}
+
+	name := SegmentBatterySparkline
+	fg := f.config.ColorHigh
+	if f.source == SparklineCPU {
+		name = SegmentCPUSparkline
+		fg = f.config.ColorCPU
+	}
+
+	return []Segment{{
+		Name:  name,
+		Text:  Sparkline(f.values),
+		Style: style.Style{}.WithFg(color.MustParse(fg)),
+	}}
+}
+
+// FormatWithStyle 使用 lipgloss 格式化迷你图（用于终端显示）
+func (f *SparklineFormatter) FormatWithStyle() string {
+	segments := f.Segments()
+	if len(segments) == 0 {
+		return ""
+	}
+	return segments[0].Style.Lipgloss().Render(segments[0].Text)
+}
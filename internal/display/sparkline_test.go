@@ -0,0 +1,43 @@
+package display
+
+import "testing"
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		values []int
+		want   string
+	}{
+		{[]int{0, 14, 29, 43, 57, 71, 86, 100}, "▁▂▃▄▅▆▇█"},
+		{[]int{-5, 120}, "▁█"},
+		{nil, ""},
+	}
+
+	for _, tt := range tests {
+		if got := Sparkline(tt.values); got != tt.want {
+			t.Errorf("Sparkline(%v) = %q, 期望 %q", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestSparklineFormatter(t *testing.T) {
+	config := newTestConfig()
+	config.SparklineLength = 3
+
+	f := NewSparklineFormatter(config, SparklineCPU)
+	f.SetValues([]int{100})
+	if segments := f.Segments(); segments != nil {
+		t.Errorf("记录不足两条时不应该显示: %+v", segments)
+	}
+
+	f.SetValues([]int{100, 0, 50, 100})
+	segments := f.Segments()
+	if len(segments) != 1 || segments[0].Name != SegmentCPUSparkline {
+		t.Fatalf("片段错误: %+v", segments)
+	}
+	if segments[0].Text != "▁▅█" {
+		t.Errorf("应该只保留最近 3 条记录，实际 %q", segments[0].Text)
+	}
+	if got := f.Format(); got != "#[fg=white]▁▅█" {
+		t.Errorf("tmux 输出错误: %q", got)
+	}
+}
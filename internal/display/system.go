@@ -6,6 +6,8 @@ import (
 
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/akayj/tmux-touchpad-battery/internal/color"
+	"github.com/akayj/tmux-touchpad-battery/internal/style"
 	"github.com/akayj/tmux-touchpad-battery/internal/system"
 	"github.com/akayj/tmux-touchpad-battery/internal/tmux"
 )
@@ -30,58 +32,61 @@ func (f *SystemInfoFormatter) SetSystemInfo(info *system.SystemInfo) {
 
 // Format 格式化系统信息为 tmux 状态栏显示
 func (f *SystemInfoFormatter) Format() string {
-	if f.systemInfo == nil || !f.systemInfo.Available {
-		return ""
-	}
+	return TmuxSerializer{}.Serialize(f.Segments())
+}
 
-	// 检查是否启用了 CPU 和 GPU 信息显示
-	if !f.config.ShowCPUInfo && !f.config.ShowGPUInfo {
-		return ""
+// Segments 返回系统信息对应的样式片段，CPU 和 GPU 各占一个片段
+func (f *SystemInfoFormatter) Segments() []Segment {
+	if f.systemInfo == nil || !f.systemInfo.Available {
+		return nil
 	}
 
-	var parts []string
-
-	// 添加前缀
-	if f.config.SystemInfoPrefix != "" {
-		parts = append(parts, f.config.SystemInfoPrefix)
-	}
+	var segments []Segment
 
 	// 添加 CPU 信息
 	if f.config.ShowCPUInfo {
-		cpuText := fmt.Sprintf("CPU:%.1f%%", f.systemInfo.CPUUsage)
-		parts = append(parts, cpuText)
+		segments = append(segments, Segment{
+			Name:  SegmentCPU,
+			Text:  fmt.Sprintf("CPU:%.1f%%", f.systemInfo.CPUUsage),
+			Style: style.Style{}.WithFg(color.MustParse(f.config.ColorCPU)),
+		})
 	}
 
 	// 添加 GPU 信息
 	if f.config.ShowGPUInfo {
+		gpuText := fmt.Sprintf("GPU:%.1f%%", f.systemInfo.GPUUsage)
 		if f.systemInfo.GPUUsage == 0 {
 			// GPU 使用率为 0 可能是因为权限问题
-			parts = append(parts, "GPU:N/A")
-		} else {
-			gpuText := fmt.Sprintf("GPU:%.1f%%", f.systemInfo.GPUUsage)
-			parts = append(parts, gpuText)
+			gpuText = "GPU:N/A"
 		}
+		segments = append(segments, Segment{
+			Name:  SegmentGPU,
+			Text:  gpuText,
+			Style: style.Style{}.WithFg(color.MustParse(f.config.ColorGPU)),
+		})
 	}
 
-	// 添加后缀
-	if f.config.SystemInfoSuffix != "" {
-		parts = append(parts, f.config.SystemInfoSuffix)
+	if len(segments) == 0 {
+		return nil
 	}
 
-	if len(parts) == 0 {
-		return ""
+	// 前缀和后缀分别附加到第一个和最后一个片段
+	if f.config.SystemInfoPrefix != "" {
+		segments[0].Text = f.config.SystemInfoPrefix + " " + segments[0].Text
+	}
+	if f.config.SystemInfoSuffix != "" {
+		last := len(segments) - 1
+		segments[last].Text = segments[last].Text + " " + f.config.SystemInfoSuffix
 	}
 
-	// 使用默认颜色
-	color := "white"
-	return fmt.Sprintf("#[fg=%s]%s", color, strings.Join(parts, " "))
+	return segments
 }
 
 // FormatWithStyle 使用 lipgloss 格式化系统信息（用于终端显示）
 func (f *SystemInfoFormatter) FormatWithStyle() string {
 	if f.systemInfo == nil || !f.systemInfo.Available {
 		return lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")).
+			Foreground(color.MustParse(f.config.ColorMuted).Lipgloss()).
 			Render("System info not available")
 	}
 
@@ -90,43 +95,39 @@ func (f *SystemInfoFormatter) FormatWithStyle() string {
 		return ""
 	}
 
+	cpuStyle := lipgloss.NewStyle().Foreground(color.MustParse(f.config.ColorCPU).Lipgloss())
+	gpuStyle := lipgloss.NewStyle().Foreground(color.MustParse(f.config.ColorGPU).Lipgloss())
+
 	var parts []string
 
 	// 添加前缀
 	if f.config.SystemInfoPrefix != "" {
-		parts = append(parts, f.config.SystemInfoPrefix)
+		parts = append(parts, cpuStyle.Render(f.config.SystemInfoPrefix))
 	}
 
 	// 添加 CPU 信息
 	if f.config.ShowCPUInfo {
 		cpuText := fmt.Sprintf("CPU: %.1f%%", f.systemInfo.CPUUsage)
-		parts = append(parts, cpuText)
+		parts = append(parts, cpuStyle.Render(cpuText))
 	}
 
 	// 添加 GPU 信息
 	if f.config.ShowGPUInfo {
 		if f.systemInfo.GPUUsage == 0 {
 			// GPU 使用率为 0 可能是因为权限问题
-			parts = append(parts, "GPU: N/A")
+			parts = append(parts, gpuStyle.Render("GPU: N/A"))
 		} else {
 			gpuText := fmt.Sprintf("GPU: %.1f%%", f.systemInfo.GPUUsage)
-			parts = append(parts, gpuText)
+			parts = append(parts, gpuStyle.Render(gpuText))
 		}
 	}
 
 	// 添加后缀
 	if f.config.SystemInfoSuffix != "" {
-		parts = append(parts, f.config.SystemInfoSuffix)
-	}
-
-	if len(parts) == 0 {
-		return ""
+		parts = append(parts, gpuStyle.Render(f.config.SystemInfoSuffix))
 	}
 
-	// 使用默认颜色
-	style := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF"))
-
-	return style.Render(strings.Join(parts, " "))
+	return strings.Join(parts, " ")
 }
 
 // FormatSystemInfo 格式化指定的系统信息为 tmux 状态栏显示（向后兼容）
@@ -0,0 +1,154 @@
+package display
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/tmux"
+)
+
+// DefaultTheme 默认主题名称
+const DefaultTheme = "default"
+
+// Theme 定义一组配色，所有颜色均使用 tmux 颜色语法
+type Theme struct {
+	Name string
+
+	// 电池各级别颜色
+	Charging string
+	Full     string
+	High     string
+	Medium   string
+	Stress   string
+
+	// 系统信息颜色以及 powerline 色块颜色
+	CPU         string
+	GPU         string
+	CPUBg       string
+	GPUBg       string
+	PowerlineFg string
+
+	// TUI 界面颜色
+	TitleFg string
+	TitleBg string
+	Detail  string
+	Muted   string
+	Error   string
+	Warning string
+}
+
+// themes 主题注册表
+var themes = map[string]*Theme{}
+
+func init() {
+	for _, theme := range []*Theme{
+		{
+			Name:     DefaultTheme,
+			Charging: "green", Full: "cyan", High: "white", Medium: "yellow", Stress: "red",
+			CPU: "white", GPU: "white", CPUBg: "colour238", GPUBg: "colour236", PowerlineFg: "black",
+			TitleFg: "#FAFAFA", TitleBg: "#7D56F4", Detail: "#888888", Muted: "#666666",
+			Error: "#FF0000", Warning: "#FFAA00",
+		},
+		{
+			// Catppuccin Mocha
+			Name:     "catppuccin",
+			Charging: "#a6e3a1", Full: "#94e2d5", High: "#cdd6f4", Medium: "#f9e2af", Stress: "#f38ba8",
+			CPU: "#89b4fa", GPU: "#cba6f7", CPUBg: "#313244", GPUBg: "#45475a", PowerlineFg: "#1e1e2e",
+			TitleFg: "#1e1e2e", TitleBg: "#cba6f7", Detail: "#a6adc8", Muted: "#6c7086",
+			Error: "#f38ba8", Warning: "#fab387",
+		},
+		{
+			Name:     "dracula",
+			Charging: "#50fa7b", Full: "#8be9fd", High: "#f8f8f2", Medium: "#f1fa8c", Stress: "#ff5555",
+			CPU: "#8be9fd", GPU: "#bd93f9", CPUBg: "#44475a", GPUBg: "#6272a4", PowerlineFg: "#282a36",
+			TitleFg: "#282a36", TitleBg: "#bd93f9", Detail: "#6272a4", Muted: "#44475a",
+			Error: "#ff5555", Warning: "#ffb86c",
+		},
+		{
+			Name:     "nord",
+			Charging: "#a3be8c", Full: "#8fbcbb", High: "#eceff4", Medium: "#ebcb8b", Stress: "#bf616a",
+			CPU: "#88c0d0", GPU: "#81a1c1", CPUBg: "#3b4252", GPUBg: "#434c5e", PowerlineFg: "#2e3440",
+			TitleFg: "#2e3440", TitleBg: "#88c0d0", Detail: "#d8dee9", Muted: "#4c566a",
+			Error: "#bf616a", Warning: "#d08770",
+		},
+		{
+			// Gruvbox Dark
+			Name:     "gruvbox",
+			Charging: "#b8bb26", Full: "#8ec07c", High: "#ebdbb2", Medium: "#fabd2f", Stress: "#fb4934",
+			CPU: "#83a598", GPU: "#d3869b", CPUBg: "#3c3836", GPUBg: "#504945", PowerlineFg: "#282828",
+			TitleFg: "#282828", TitleBg: "#fe8019", Detail: "#a89984", Muted: "#665c54",
+			Error: "#fb4934", Warning: "#fe8019",
+		},
+		{
+			// Tokyo Night
+			Name:     "tokyonight",
+			Charging: "#9ece6a", Full: "#7dcfff", High: "#c0caf5", Medium: "#e0af68", Stress: "#f7768e",
+			CPU: "#7aa2f7", GPU: "#bb9af7", CPUBg: "#292e42", GPUBg: "#3b4261", PowerlineFg: "#1a1b26",
+			TitleFg: "#1a1b26", TitleBg: "#7aa2f7", Detail: "#a9b1d6", Muted: "#565f89",
+			Error: "#f7768e", Warning: "#ff9e64",
+		},
+	} {
+		RegisterTheme(theme)
+	}
+}
+
+// RegisterTheme 注册主题，同名主题会被覆盖
+func RegisterTheme(theme *Theme) {
+	themes[theme.Name] = theme
+}
+
+// LookupTheme 按名称查找主题
+func LookupTheme(name string) (*Theme, bool) {
+	theme, ok := themes[name]
+	return theme, ok
+}
+
+// ThemeNames 返回所有已注册的主题名称
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyTheme 使用 config.Theme 指定的主题填充未设置的颜色配置
+// 单独设置的 @tpb_color_* 选项优先于主题；主题不存在时使用默认主题并返回错误
+func ApplyTheme(config *tmux.Config) error {
+	var err error
+
+	theme, ok := LookupTheme(config.Theme)
+	if !ok {
+		err = fmt.Errorf("@tpb_theme: 未知主题 %q，可用主题: %v", config.Theme, ThemeNames())
+		theme = themes[DefaultTheme]
+	}
+
+	for _, field := range []struct {
+		value *string
+		theme string
+	}{
+		{&config.ColorCharging, theme.Charging},
+		{&config.ColorFull, theme.Full},
+		{&config.ColorHigh, theme.High},
+		{&config.ColorMedium, theme.Medium},
+		{&config.ColorStress, theme.Stress},
+		{&config.ColorCPU, theme.CPU},
+		{&config.ColorGPU, theme.GPU},
+		{&config.PowerlineCPUBg, theme.CPUBg},
+		{&config.PowerlineGPUBg, theme.GPUBg},
+		{&config.PowerlineFg, theme.PowerlineFg},
+		{&config.ColorTitleFg, theme.TitleFg},
+		{&config.ColorTitleBg, theme.TitleBg},
+		{&config.ColorDetail, theme.Detail},
+		{&config.ColorMuted, theme.Muted},
+		{&config.ColorError, theme.Error},
+		{&config.ColorWarning, theme.Warning},
+	} {
+		if *field.value == "" {
+			*field.value = field.theme
+		}
+	}
+
+	return err
+}
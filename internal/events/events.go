@@ -0,0 +1,192 @@
+// Package events 比较相邻两次采样，生成电池和系统事件并运行用户配置的钩子命令
+package events
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/snapshot"
+)
+
+// 事件类型
+const (
+	BatteryLow             = "battery.low"
+	BatteryCritical        = "battery.critical"
+	BatteryChargingStarted = "battery.charging_started"
+	BatteryFull            = "battery.full"
+	DeviceConnected        = "device.connected"
+	DeviceDisconnected     = "device.disconnected"
+	CPUHigh                = "cpu.high"
+)
+
+// Types 所有事件类型
+var Types = []string{
+	BatteryLow,
+	BatteryCritical,
+	BatteryChargingStarted,
+	BatteryFull,
+	DeviceConnected,
+	DeviceDisconnected,
+	CPUHigh,
+}
+
+// Thresholds 触发事件的阈值
+type Thresholds struct {
+	Low      int
+	Critical int
+	Full     int
+	CPUHigh  float64
+}
+
+// Device 事件中的设备信息
+type Device struct {
+	Name       string `json:"name"`
+	Serial     string `json:"serial,omitempty"`
+	Address    string `json:"address,omitempty"`
+	Percentage int    `json:"percentage"`
+	Charging   bool   `json:"charging"`
+}
+
+// key 返回设备标识，优先使用序列号
+func (d Device) key() string {
+	if d.Serial != "" {
+		return d.Serial
+	}
+	return d.Name
+}
+
+// Event 表示一个事件
+type Event struct {
+	Type   string    `json:"event"`
+	Time   time.Time `json:"time"`
+	Device *Device   `json:"device,omitempty"`
+	CPU    *float64  `json:"cpu_usage_percent,omitempty"`
+}
+
+// Message 返回事件的简短描述，用于通知和 webhook
+func (ev Event) Message() string {
+	name := "Touchpad"
+	percent := 0
+	if ev.Device != nil {
+		percent = ev.Device.Percentage
+		if ev.Device.Name != "" {
+			name = ev.Device.Name
+		}
+	}
+
+	switch ev.Type {
+	case BatteryLow:
+		return fmt.Sprintf("%s battery low (%d%%)", name, percent)
+	case BatteryCritical:
+		return fmt.Sprintf("%s battery critical (%d%%)", name, percent)
+	case BatteryChargingStarted:
+		return fmt.Sprintf("%s started charging (%d%%)", name, percent)
+	case BatteryFull:
+		return fmt.Sprintf("%s fully charged (%d%%)", name, percent)
+	case DeviceConnected:
+		return fmt.Sprintf("%s connected (%d%%)", name, percent)
+	case DeviceDisconnected:
+		return fmt.Sprintf("%s disconnected", name)
+	case CPUHigh:
+		if ev.CPU != nil {
+			return fmt.Sprintf("CPU usage high (%.1f%%)", *ev.CPU)
+		}
+		return "CPU usage high"
+	}
+	return ev.Type
+}
+
+// State 生成事件所需的采样摘要，可以持久化以便单次运行的 tmux 模式比较前后两次采样
+type State struct {
+	Time time.Time `json:"time"`
+	// Devices 为 nil 表示设备列表获取失败，此时不生成连接/断开事件
+	Devices []Device `json:"devices"`
+	CPU     *float64 `json:"cpu_usage_percent,omitempty"`
+}
+
+// StateFromSnapshot 从采样结果生成摘要
+func StateFromSnapshot(s *snapshot.Snapshot) State {
+	st := State{Time: s.Time}
+
+	if s.DevicesErr == nil {
+		st.Devices = []Device{}
+		for _, d := range s.Devices {
+			if !d.Available {
+				continue
+			}
+			st.Devices = append(st.Devices, Device{
+				Name:       d.Name,
+				Serial:     d.Serial,
+				Address:    d.Address,
+				Percentage: d.Percentage,
+				Charging:   d.IsCharging,
+			})
+		}
+	}
+
+	if s.SystemErr == nil && s.System != nil && s.System.Available {
+		cpu := s.System.CPUUsage
+		st.CPU = &cpu
+	}
+
+	return st
+}
+
+// Diff 比较前后两次采样，返回期间发生的事件
+// 阈值事件只在跨越阈值的那次采样触发；一次跨越多个阈值时依次触发
+func Diff(prev, cur State, th Thresholds) []Event {
+	var events []Event
+	emit := func(typ string, d *Device) {
+		ev := Event{Type: typ, Time: cur.Time, Device: d}
+		if d == nil {
+			ev.CPU = cur.CPU
+		}
+		events = append(events, ev)
+	}
+
+	if prev.Devices != nil && cur.Devices != nil {
+		before := make(map[string]Device, len(prev.Devices))
+		for _, d := range prev.Devices {
+			before[d.key()] = d
+		}
+		after := make(map[string]bool, len(cur.Devices))
+
+		for i := range cur.Devices {
+			d := &cur.Devices[i]
+			after[d.key()] = true
+
+			old, ok := before[d.key()]
+			if !ok {
+				emit(DeviceConnected, d)
+				continue
+			}
+
+			if d.Charging && !old.Charging {
+				emit(BatteryChargingStarted, d)
+			}
+			if d.Percentage >= th.Full && old.Percentage < th.Full {
+				emit(BatteryFull, d)
+			}
+			// 与状态栏的低电量样式一致，电量低于阈值（不含等于）时触发
+			if !d.Charging && d.Percentage < th.Low && old.Percentage >= th.Low {
+				emit(BatteryLow, d)
+			}
+			if !d.Charging && d.Percentage < th.Critical && old.Percentage >= th.Critical {
+				emit(BatteryCritical, d)
+			}
+		}
+
+		for i := range prev.Devices {
+			d := &prev.Devices[i]
+			if !after[d.key()] {
+				emit(DeviceDisconnected, d)
+			}
+		}
+	}
+
+	if prev.CPU != nil && cur.CPU != nil && *cur.CPU >= th.CPUHigh && *prev.CPU < th.CPUHigh {
+		emit(CPUHigh, nil)
+	}
+
+	return events
+}
@@ -0,0 +1,182 @@
+package events
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/tmux"
+)
+
+var testThresholds = Thresholds{Low: 30, Critical: 10, Full: 100, CPUHigh: 90}
+
+func cpu(v float64) *float64 {
+	return &v
+}
+
+func TestDiff(t *testing.T) {
+	trackpad := func(percent int, charging bool) Device {
+		return Device{Name: "Magic Trackpad", Serial: "CC01", Percentage: percent, Charging: charging}
+	}
+	mouse := Device{Name: "Magic Mouse", Serial: "MM01", Percentage: 80}
+
+	tests := []struct {
+		name string
+		prev State
+		cur  State
+		want []string
+	}{
+		{"无变化", State{Devices: []Device{trackpad(50, false)}}, State{Devices: []Device{trackpad(49, false)}}, nil},
+		{"跌破低电量阈值", State{Devices: []Device{trackpad(30, false)}}, State{Devices: []Device{trackpad(29, false)}}, []string{BatteryLow}},
+		{"等于阈值不触发", State{Devices: []Device{trackpad(31, false)}}, State{Devices: []Device{trackpad(30, false)}}, nil},
+		{"已低于阈值不重复", State{Devices: []Device{trackpad(29, false)}}, State{Devices: []Device{trackpad(28, false)}}, nil},
+		{"一次跨越两个阈值", State{Devices: []Device{trackpad(50, false)}}, State{Devices: []Device{trackpad(5, false)}}, []string{BatteryLow, BatteryCritical}},
+		{"开始充电", State{Devices: []Device{trackpad(20, false)}}, State{Devices: []Device{trackpad(20, true)}}, []string{BatteryChargingStarted}},
+		{"充满", State{Devices: []Device{trackpad(99, true)}}, State{Devices: []Device{trackpad(100, true)}}, []string{BatteryFull}},
+		{"充电时不触发低电量", State{Devices: []Device{trackpad(30, true)}}, State{Devices: []Device{trackpad(29, true)}}, nil},
+		{"设备连接", State{Devices: []Device{trackpad(50, false)}}, State{Devices: []Device{trackpad(50, false), mouse}}, []string{DeviceConnected}},
+		{"设备断开", State{Devices: []Device{trackpad(50, false), mouse}}, State{Devices: []Device{mouse}}, []string{DeviceDisconnected}},
+		{"设备列表获取失败", State{Devices: []Device{trackpad(50, false)}}, State{}, nil},
+		{"CPU 过高", State{Devices: []Device{}, CPU: cpu(50)}, State{Devices: []Device{}, CPU: cpu(95)}, []string{CPUHigh}},
+		{"CPU 持续过高不重复", State{CPU: cpu(95)}, State{CPU: cpu(97)}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, ev := range Diff(tt.prev, tt.cur, testThresholds) {
+				got = append(got, ev.Type)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("期望 %v, 实际 %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWatcherPersistedState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), StateFile)
+
+	// 模拟每次状态栏重绘都是新进程
+	observe := func(percent int) []Event {
+		w := &Watcher{Thresholds: testThresholds, StatePath: path}
+		evs, err := w.Observe(State{Time: time.Now(), Devices: []Device{{Name: "Magic Trackpad", Percentage: percent}}})
+		if err != nil {
+			t.Fatalf("处理采样失败: %v", err)
+		}
+		return evs
+	}
+
+	if evs := observe(30); len(evs) != 0 {
+		t.Errorf("第一次采样不应该产生事件: %+v", evs)
+	}
+	if evs := observe(29); len(evs) != 1 || evs[0].Type != BatteryLow {
+		t.Errorf("应该产生 battery.low: %+v", evs)
+	}
+	if evs := observe(29); len(evs) != 0 {
+		t.Errorf("不应该重复产生事件: %+v", evs)
+	}
+}
+
+// flushHandler 记录收到的事件和 Flush 调用次数
+type flushHandler struct {
+	events  []string
+	flushes int
+}
+
+func (h *flushHandler) HandleEvent(ev Event) error {
+	h.events = append(h.events, ev.Type)
+	return nil
+}
+
+func (h *flushHandler) Flush() error {
+	h.flushes++
+	return nil
+}
+
+func TestWatcherFlush(t *testing.T) {
+	h := &flushHandler{}
+	w := &Watcher{Thresholds: testThresholds, Handlers: []Handler{h}}
+
+	for _, percent := range []int{50, 49, 29, 28} {
+		if _, err := w.Observe(State{Time: time.Now(), Devices: []Device{{Name: "Magic Trackpad", Percentage: percent}}}); err != nil {
+			t.Fatalf("处理采样失败: %v", err)
+		}
+	}
+
+	// 产生 battery.low 的采样不调用 Flush，其余三次都调用
+	if len(h.events) != 1 || h.flushes != 3 {
+		t.Errorf("events = %v, flushes = %d, want [battery.low] and 3", h.events, h.flushes)
+	}
+}
+
+func TestRunner(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	runner := &Runner{Hooks: map[string][]string{
+		BatteryLow: {`printf '%s %s %s\n' "$TPB_EVENT" "$TPB_DEVICE" "$TPB_PERCENT" > ` + out + `; cat >> ` + out},
+		"*":        {"exit 1"},
+	}}
+
+	ev := Event{
+		Type:   BatteryLow,
+		Time:   time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Device: &Device{Name: "Magic Trackpad", Percentage: 30},
+	}
+	if err := runner.HandleEvent(ev); err == nil {
+		t.Errorf("失败的命令应该返回错误")
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("钩子没有运行: %v", err)
+	}
+	lines := strings.SplitN(string(data), "\n", 2)
+	if lines[0] != "battery.low Magic Trackpad 30" {
+		t.Errorf("环境变量错误: %q", lines[0])
+	}
+	if !strings.Contains(lines[1], `"event":"battery.low"`) || !strings.Contains(lines[1], `"percentage":30`) {
+		t.Errorf("标准输入 JSON 错误: %q", lines[1])
+	}
+}
+
+func TestRunnerDetach(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	runner := &Runner{
+		Hooks:  map[string][]string{BatteryLow: {`sleep 0.1; cat > ` + out + `.tmp; mv ` + out + `.tmp ` + out}},
+		Detach: true,
+	}
+
+	ev := Event{Type: BatteryLow, Time: time.Now(), Device: &Device{Name: "Magic Trackpad", Percentage: 30}}
+	start := time.Now()
+	if err := runner.HandleEvent(ev); err != nil {
+		t.Fatalf("启动钩子失败: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 100*time.Millisecond {
+		t.Errorf("后台运行时不应等待命令结束，耗时 %s", elapsed)
+	}
+
+	// 命令在后台读取完整的标准输入
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, err := os.ReadFile(out)
+		if err == nil {
+			if !strings.Contains(string(data), `"event":"battery.low"`) {
+				t.Errorf("标准输入 JSON 错误: %q", data)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("钩子没有运行")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestHookEvents(t *testing.T) {
+	if !reflect.DeepEqual(tmux.HookEvents, Types) {
+		t.Errorf("tmux.HookEvents 与事件类型不一致: %v != %v", tmux.HookEvents, Types)
+	}
+}
@@ -0,0 +1,278 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/snapshot"
+	"github.com/akayj/tmux-touchpad-battery/internal/state"
+	"github.com/akayj/tmux-touchpad-battery/internal/tmux"
+)
+
+// DefaultHookTimeout 单个钩子命令的默认超时
+const DefaultHookTimeout = 10 * time.Second
+
+// StateFile 状态目录下保存上一次采样摘要的文件名
+const StateFile = "events.json"
+
+// Handler 处理事件
+type Handler interface {
+	HandleEvent(ev Event) error
+}
+
+// Flusher 由带有重试队列的处理器实现，如 webhook
+// 没有事件的采样中 Watcher 调用 Flush 补发之前失败的请求
+type Flusher interface {
+	Flush() error
+}
+
+// Runner 为事件运行用户配置的 shell 命令
+// 事件数据通过 TPB_* 环境变量传递，同时以 JSON 写入标准输入
+type Runner struct {
+	// Hooks 事件类型到命令的映射，键为 "*" 的命令对所有事件运行
+	Hooks   map[string][]string
+	Timeout time.Duration
+	// Detach 为 true 时启动命令后不等待它结束，也不受 Timeout 限制，用于单次运行的状态栏重绘
+	Detach bool
+}
+
+// Empty 判断是否没有配置任何钩子
+func (r *Runner) Empty() bool {
+	for _, commands := range r.Hooks {
+		if len(commands) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// HandleEvent 实现 Handler，运行事件对应的所有命令，某个命令失败不影响其他命令
+func (r *Runner) HandleEvent(ev Event) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, command := range append(r.Hooks[ev.Type], r.Hooks["*"]...) {
+		if err := r.run(command, ev, payload); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %q: %w", ev.Type, command, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// run 运行单个命令
+func (r *Runner) run(command string, ev Event, payload []byte) error {
+	if r.Detach {
+		return r.start(command, ev, payload)
+	}
+
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), Env(ev)...)
+	cmd.Stdin = bytes.NewReader(payload)
+	return cmd.Run()
+}
+
+// start 在后台启动单个命令，事件数据通过管道写入标准输入
+// 管道在启动前写好并关闭写端，本进程退出后命令仍能读到完整的数据
+func (r *Runner) start(command string, ev Event, payload []byte) error {
+	stdin, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer stdin.Close()
+
+	// 事件数据远小于管道缓冲区，写入不会阻塞
+	_, err = w.Write(payload)
+	w.Close()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = append(os.Environ(), Env(ev)...)
+	cmd.Stdin = stdin
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+// Env 返回事件对应的环境变量
+func Env(ev Event) []string {
+	env := []string{
+		"TPB_EVENT=" + ev.Type,
+		"TPB_TIME=" + ev.Time.Format(time.RFC3339),
+	}
+	if d := ev.Device; d != nil {
+		env = append(env,
+			"TPB_DEVICE="+d.Name,
+			"TPB_SERIAL="+d.Serial,
+			"TPB_ADDRESS="+d.Address,
+			"TPB_PERCENT="+strconv.Itoa(d.Percentage),
+			"TPB_CHARGING="+strconv.FormatBool(d.Charging),
+		)
+	}
+	if ev.CPU != nil {
+		env = append(env, "TPB_CPU="+strconv.FormatFloat(*ev.CPU, 'f', 1, 64))
+	}
+	return env
+}
+
+// Watcher 比较相邻两次采样并运行钩子
+// 设置 StatePath 时上一次的采样摘要保存在文件中，
+// 常驻模式、TUI 和单次运行的 tmux 模式共享同一文件，每个事件只触发一次
+type Watcher struct {
+	Thresholds Thresholds
+	Handlers   []Handler
+	StatePath  string
+
+	mu   sync.Mutex
+	prev *State
+}
+
+// NewWatcher 根据 tmux 配置创建事件监视器，extra 为钩子命令之外的其他处理器
+// 没有配置任何钩子且没有其他处理器时返回 nil
+func NewWatcher(config *tmux.Config, statePath string, extra ...Handler) *Watcher {
+	var handlers []Handler
+
+	runner := &Runner{Hooks: make(map[string][]string)}
+	for event, command := range config.Hooks {
+		runner.Hooks[event] = append(runner.Hooks[event], command)
+	}
+	if !runner.Empty() {
+		handlers = append(handlers, runner)
+	}
+
+	for _, h := range extra {
+		if h != nil {
+			handlers = append(handlers, h)
+		}
+	}
+	if len(handlers) == 0 {
+		return nil
+	}
+
+	return &Watcher{
+		Thresholds: Thresholds{
+			Low:      config.StressThreshold,
+			Critical: config.CriticalThreshold,
+			Full:     config.FullThreshold,
+			CPUHigh:  float64(config.CPUHighThreshold),
+		},
+		Handlers:  handlers,
+		StatePath: statePath,
+	}
+}
+
+// DetachHooks 让钩子命令在后台运行，不等待它们结束
+func (w *Watcher) DetachHooks() {
+	for _, h := range w.Handlers {
+		if r, ok := h.(*Runner); ok {
+			r.Detach = true
+		}
+	}
+}
+
+// StatePath 返回状态目录下共享的采样摘要文件路径
+func StatePath() (string, error) {
+	dir, err := state.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, StateFile), nil
+}
+
+// Handle 实现 daemon.Sink
+func (w *Watcher) Handle(s *snapshot.Snapshot) error {
+	_, err := w.Observe(StateFromSnapshot(s))
+	return err
+}
+
+// Observe 记录一次采样，返回与上一次采样相比发生的事件并运行对应的钩子
+func (w *Watcher) Observe(cur State) ([]Event, error) {
+	w.mu.Lock()
+	prev := w.prev
+	if w.StatePath != "" {
+		if saved, err := loadState(w.StatePath); err == nil {
+			prev = saved
+		}
+	}
+	w.prev = &cur
+	w.mu.Unlock()
+
+	var errs []error
+	if w.StatePath != "" {
+		if err := saveState(w.StatePath, cur); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	var evs []Event
+	if prev != nil {
+		evs = Diff(*prev, cur, w.Thresholds)
+	}
+	for _, ev := range evs {
+		for _, h := range w.Handlers {
+			if err := h.HandleEvent(ev); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	// 有事件时处理器已经在发送前补发过队列
+	if len(evs) == 0 {
+		for _, h := range w.Handlers {
+			if f, ok := h.(Flusher); ok {
+				if err := f.Flush(); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+	return evs, errors.Join(errs...)
+}
+
+// loadState 读取上一次的采样摘要，文件不存在时返回 nil
+func loadState(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var st State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// saveState 保存事件检测状态
+func saveState(path string, st State) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return state.WriteFile(path, data)
+}
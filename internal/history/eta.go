@@ -0,0 +1,127 @@
+package history
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// 剩余时间估算参数
+const (
+	// ETAWindow 只使用最近这段时间内的记录估算放电速率
+	ETAWindow = 14 * 24 * time.Hour
+	// ETAMinSpan 放电窗口至少覆盖的时长，太短时不估算
+	ETAMinSpan = time.Hour
+	// ETAMinSamples 放电窗口至少包含的记录数
+	ETAMinSamples = 3
+	// ETARiseTolerance 电量回升超过该值视为充过电，低于该值视为读数抖动
+	ETARiseTolerance = 2
+)
+
+// EstimateETA 根据同一设备按时间排序的记录估算电量耗尽的剩余时间
+// 使用最近一次充电之后的放电窗口做线性回归；正在充电、数据不足或电量没有下降时返回 false
+func EstimateETA(samples []Sample, now time.Time) (time.Duration, bool) {
+	window := dischargeWindow(samples, now)
+	if len(window) < ETAMinSamples {
+		return 0, false
+	}
+	if window[len(window)-1].Time.Sub(window[0].Time) < ETAMinSpan {
+		return 0, false
+	}
+
+	// 以小时为单位拟合 percent = a + b*t
+	origin := window[0].Time
+	var sumX, sumY, sumXX, sumXY float64
+	for _, s := range window {
+		x := s.Time.Sub(origin).Hours()
+		y := float64(s.Percent)
+		sumX += x
+		sumY += y
+		sumXX += x * x
+		sumXY += x * y
+	}
+	n := float64(len(window))
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		return 0, false
+	}
+	slope := (n*sumXY - sumX*sumY) / denom
+	intercept := (sumY - slope*sumX) / n
+
+	// 电量没有下降（平台期），无法估算
+	if slope >= 0 {
+		return 0, false
+	}
+
+	current := intercept + slope*now.Sub(origin).Hours()
+	current = math.Max(0, math.Min(100, current))
+
+	hours := current / -slope
+	return time.Duration(hours * float64(time.Hour)), true
+}
+
+// dischargeWindow 返回最近一次充电之后、且在 ETAWindow 之内的放电记录
+// 充电状态或电量明显回升都视为充电，小幅回升视为读数抖动
+func dischargeWindow(samples []Sample, now time.Time) []Sample {
+	if len(samples) == 0 || samples[len(samples)-1].Charging {
+		return nil
+	}
+
+	start := 0
+	lowest := math.MaxInt
+	for i, s := range samples {
+		switch {
+		case now.Sub(s.Time) > ETAWindow:
+			start, lowest = i+1, math.MaxInt
+		case s.Charging:
+			start, lowest = i+1, math.MaxInt
+		case s.Percent > lowest+ETARiseTolerance:
+			start, lowest = i, s.Percent
+		default:
+			lowest = min(lowest, s.Percent)
+		}
+	}
+
+	return samples[start:]
+}
+
+// FormatETA 将剩余时间格式化为简短文本，如 ~2d、~5h、~40m
+func FormatETA(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("~%dd", int(math.Round(d.Hours()/24)))
+	case d >= time.Hour:
+		return fmt.Sprintf("~%dh", int(math.Round(d.Hours())))
+	default:
+		return fmt.Sprintf("~%dm", max(1, int(math.Round(d.Minutes()))))
+	}
+}
+
+// ETA 查询指定设备的历史记录并估算剩余时间
+// device 为空时使用最近一次记录的外设
+func (s *Store) ETA(device string, now time.Time) (time.Duration, bool) {
+	samples, err := s.Query(Query{Since: now.Add(-ETAWindow)})
+	if err != nil {
+		return 0, false
+	}
+
+	key := device
+	for i := len(samples) - 1; key == "" && i >= 0; i-- {
+		if samples[i].Device != DeviceCPU {
+			key = samples[i].key()
+		}
+	}
+	if key == "" {
+		return 0, false
+	}
+
+	var filtered []Sample
+	q := Query{Device: key}
+	for _, sample := range samples {
+		if q.match(sample) {
+			filtered = append(filtered, sample)
+		}
+	}
+
+	return EstimateETA(filtered, now)
+}
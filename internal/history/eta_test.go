@@ -0,0 +1,91 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+// series 生成从 start 开始每隔 step 一条的放电记录
+func series(start time.Time, step time.Duration, percents ...int) []Sample {
+	samples := make([]Sample, 0, len(percents))
+	for i, p := range percents {
+		samples = append(samples, Sample{Time: start.Add(time.Duration(i) * step), Device: "Trackpad", Percent: p})
+	}
+	return samples
+}
+
+func TestEstimateETA(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 每小时下降 1%，从 50% 开始
+	linear := series(start, time.Hour, 50, 49, 48, 47, 46, 45)
+
+	// 前半段在充电，充满后开始放电：只应该使用充电之后的记录
+	charged := series(start, time.Hour, 20, 40, 60, 80, 100, 98, 96, 94)
+	for i := 0; i < 5; i++ {
+		charged[i].Charging = true
+	}
+
+	// 未上报充电状态，但电量从 10% 跳到 100%，视为充过电
+	jumped := series(start, time.Hour, 14, 12, 10, 100, 98, 96, 94)
+
+	// 读数在下降趋势上 ±1% 抖动
+	noisy := series(start, time.Hour, 60, 59, 59, 57, 58, 55, 55, 53, 54, 51)
+
+	tests := []struct {
+		name    string
+		samples []Sample
+		now     time.Time
+		want    time.Duration
+		ok      bool
+	}{
+		{"线性放电", linear, start.Add(5 * time.Hour), 45 * time.Hour, true},
+		{"充电后重新计算", charged, start.Add(7 * time.Hour), 47 * time.Hour, true},
+		{"电量跳升后重新计算", jumped, start.Add(6 * time.Hour), 47 * time.Hour, true},
+		{"读数抖动", noisy, start.Add(9 * time.Hour), 51 * time.Hour, true},
+		{"平台期", series(start, time.Hour, 100, 100, 100, 100), start.Add(3 * time.Hour), 0, false},
+		{"正在充电", charged[:5], start.Add(4 * time.Hour), 0, false},
+		{"记录太少", series(start, time.Hour, 50, 49), start.Add(time.Hour), 0, false},
+		{"时间跨度太短", series(start, time.Minute, 50, 49, 48, 47), start.Add(3 * time.Minute), 0, false},
+		{"记录过期", linear, start.Add(30 * 24 * time.Hour), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := EstimateETA(tt.samples, tt.now)
+			if ok != tt.ok {
+				t.Fatalf("是否可估算: 期望 %v, 实际 %v (%v)", tt.ok, ok, got)
+			}
+			if !ok {
+				return
+			}
+			// 允许 10% 误差
+			diff := got - tt.want
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff > tt.want/10 {
+				t.Errorf("剩余时间: 期望约 %v, 实际 %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFormatETA(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{50 * time.Hour, "~2d"},
+		{10 * 24 * time.Hour, "~10d"},
+		{5*time.Hour + 20*time.Minute, "~5h"},
+		{40 * time.Minute, "~40m"},
+		{10 * time.Second, "~1m"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatETA(tt.d); got != tt.want {
+			t.Errorf("FormatETA(%v) = %q, 期望 %q", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+// Package history 以追加写入的 JSONL 文件记录设备电量历史，并支持轮转、压缩和查询
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/snapshot"
+	"github.com/akayj/tmux-touchpad-battery/internal/state"
+)
+
+// 历史文件名：新采样追加到 ActiveFile，轮转时与 ArchiveFile 合并压缩
+const (
+	ActiveFile  = "history.jsonl"
+	ArchiveFile = "history.archive.jsonl"
+)
+
+// compactingFile 压缩开始时活动文件被移到这里，压缩期间的新记录写入新的活动文件
+const compactingFile = "history.compacting.jsonl"
+
+// 默认参数
+const (
+	DefaultMinInterval   = time.Minute
+	DefaultMaxSize       = 256 << 10
+	DefaultRetention     = 30 * 24 * time.Hour
+	DefaultCompactAfter  = 24 * time.Hour
+	DefaultCompactBucket = 10 * time.Minute
+)
+
+// DeviceCPU CPU 使用率记录使用的设备名，Percent 为取整后的使用率
+const DeviceCPU = "cpu"
+
+// Sample 表示某个设备在某一时刻的电量
+type Sample struct {
+	Time     time.Time `json:"t"`
+	Device   string    `json:"device"`
+	Serial   string    `json:"serial,omitempty"`
+	Percent  int       `json:"percent"`
+	Charging bool      `json:"charging"`
+}
+
+// key 返回设备标识，优先使用序列号
+func (s Sample) key() string {
+	if s.Serial != "" {
+		return s.Serial
+	}
+	return s.Device
+}
+
+// SamplesFromSnapshot 将采样结果中可用的设备和 CPU 使用率转换为历史记录
+func SamplesFromSnapshot(s *snapshot.Snapshot) []Sample {
+	var samples []Sample
+	for _, d := range s.Devices {
+		if !d.Available {
+			continue
+		}
+		samples = append(samples, Sample{
+			Time:     s.Time,
+			Device:   d.Name,
+			Serial:   d.Serial,
+			Percent:  d.Percentage,
+			Charging: d.IsCharging,
+		})
+	}
+	if s.System != nil && s.System.Available {
+		samples = append(samples, Sample{
+			Time:    s.Time,
+			Device:  DeviceCPU,
+			Percent: int(math.Round(s.System.CPUUsage)),
+		})
+	}
+	return samples
+}
+
+// Query 表示历史查询条件，零值表示不限制
+type Query struct {
+	// Device 匹配设备名称或序列号，不区分大小写
+	Device string
+	Since  time.Time
+	Until  time.Time
+}
+
+// match 判断记录是否满足查询条件
+func (q Query) match(s Sample) bool {
+	if q.Device != "" && !strings.EqualFold(q.Device, s.Device) && !strings.EqualFold(q.Device, s.Serial) {
+		return false
+	}
+	if !q.Since.IsZero() && s.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && s.Time.After(q.Until) {
+		return false
+	}
+	return true
+}
+
+// Store 历史记录存储
+type Store struct {
+	Dir string
+
+	// MinInterval 两次记录之间的最小间隔，用于限制写入频率
+	MinInterval time.Duration
+	// MaxSize 活动文件超过该大小（字节）时轮转
+	MaxSize int64
+	// Retention 超过该时长的记录在压缩时删除
+	Retention time.Duration
+	// CompactAfter 超过该时长的记录在压缩时按 CompactBucket 降采样
+	CompactAfter  time.Duration
+	CompactBucket time.Duration
+}
+
+// NewStore 创建使用默认参数的存储
+func NewStore(dir string) *Store {
+	return &Store{
+		Dir:           dir,
+		MinInterval:   DefaultMinInterval,
+		MaxSize:       DefaultMaxSize,
+		Retention:     DefaultRetention,
+		CompactAfter:  DefaultCompactAfter,
+		CompactBucket: DefaultCompactBucket,
+	}
+}
+
+// Open 打开状态目录下的历史存储
+func Open() (*Store, error) {
+	dir, err := state.Dir()
+	if err != nil {
+		return nil, err
+	}
+	return NewStore(dir), nil
+}
+
+// Due 判断距离上次记录是否已超过最小间隔
+// 以活动文件的修改时间作为上次记录时间，不需要读取文件内容
+func (s *Store) Due(now time.Time) bool {
+	if s.MinInterval <= 0 {
+		return true
+	}
+	info, err := os.Stat(filepath.Join(s.Dir, ActiveFile))
+	if err != nil {
+		return true
+	}
+	return now.Sub(info.ModTime()) >= s.MinInterval
+}
+
+// Record 追加一批记录，距离上次记录不足最小间隔时跳过
+func (s *Store) Record(samples []Sample) error {
+	if len(samples) == 0 || !s.Due(samples[0].Time) {
+		return nil
+	}
+
+	var b strings.Builder
+	for _, sample := range samples {
+		line, err := json.Marshal(sample)
+		if err != nil {
+			return err
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+
+	path := filepath.Join(s.Dir, ActiveFile)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err == nil && s.MaxSize > 0 && info.Size() > s.MaxSize {
+		return s.Compact(samples[0].Time)
+	}
+	return nil
+}
+
+// Handle 实现 daemon.Sink，记录采样结果中的设备电量
+func (s *Store) Handle(snap *snapshot.Snapshot) error {
+	return s.Record(SamplesFromSnapshot(snap))
+}
+
+// Compact 将活动文件合并到归档文件，删除过期记录并对较早的记录降采样
+//
+// 活动文件先通过硬链接移到 compactingFile 再合并，之后并发追加的记录写入新的活动文件，不会随合并丢失；
+// compactingFile 已存在（另一次压缩正在进行或上次中途失败）时链接失败，直接合并已有的文件
+func (s *Store) Compact(now time.Time) error {
+	archivePath := filepath.Join(s.Dir, ArchiveFile)
+	activePath := filepath.Join(s.Dir, ActiveFile)
+	compactingPath := filepath.Join(s.Dir, compactingFile)
+
+	if err := os.Link(activePath, compactingPath); err == nil {
+		if err := os.Remove(activePath); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, fs.ErrExist) && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	// 先读取待合并的记录再读取归档：并发的压缩已删除 compactingFile 时，读到的归档已经包含这些记录
+	pending, err := readFile(compactingPath, Query{})
+	if err != nil {
+		return err
+	}
+	archived, err := readFile(archivePath, Query{})
+	if err != nil {
+		return err
+	}
+
+	samples := compact(append(archived, pending...), now, s.Retention, s.CompactAfter, s.CompactBucket)
+	if err := writeFile(archivePath, samples); err != nil {
+		return err
+	}
+
+	// 归档写入成功后再删除，中途失败最多产生重复记录，下次压缩时去重
+	if err := os.Remove(compactingPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Query 按条件查询历史记录，结果按时间排序
+func (s *Store) Query(q Query) ([]Sample, error) {
+	var result []Sample
+	for _, name := range []string{ArchiveFile, compactingFile, ActiveFile} {
+		samples, err := readFile(filepath.Join(s.Dir, name), q)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, samples...)
+	}
+
+	sortByTime(result)
+	return result, nil
+}
+
+// Series 返回指定设备 since 之后最近 n 条记录的百分比，按时间从旧到新排列
+// 从新到旧依次读取活动文件和归档文件，已经找到 n 条或归档文件在 since 之前写入时不再读取归档
+func (s *Store) Series(device string, n int, since time.Time) ([]int, error) {
+	q := Query{Device: device, Since: since}
+
+	var samples []Sample
+	for _, name := range []string{ActiveFile, compactingFile, ArchiveFile} {
+		if n > 0 && len(samples) >= n {
+			break
+		}
+
+		path := filepath.Join(s.Dir, name)
+		if name == ArchiveFile && !since.IsZero() {
+			if info, err := os.Stat(path); err == nil && info.ModTime().Before(since) {
+				break
+			}
+		}
+
+		older, err := readFile(path, q)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(older, samples...)
+	}
+
+	sortByTime(samples)
+	if n > 0 && len(samples) > n {
+		samples = samples[len(samples)-n:]
+	}
+
+	values := make([]int, 0, len(samples))
+	for _, sample := range samples {
+		values = append(values, sample.Percent)
+	}
+	return values, nil
+}
+
+// sortByTime 按时间排序，时间相同的记录保持原来的顺序
+func sortByTime(samples []Sample) {
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].Time.Before(samples[j].Time)
+	})
+}
+
+// compact 按时间排序、去重，删除过期记录，并将较早的记录按设备和时间桶降采样（每桶保留第一条）
+func compact(samples []Sample, now time.Time, retention, after, bucket time.Duration) []Sample {
+	sortByTime(samples)
+
+	type bucketKey struct {
+		device string
+		slot   int64
+	}
+	seen := make(map[bucketKey]bool)
+
+	var result []Sample
+	for _, sample := range samples {
+		age := now.Sub(sample.Time)
+		if retention > 0 && age > retention {
+			continue
+		}
+
+		key := bucketKey{device: sample.key(), slot: sample.Time.UnixNano()}
+		if bucket > 0 && age > after {
+			key.slot = sample.Time.UnixNano() / int64(bucket)
+			// 与近期记录区分开
+			key.device = "\x00" + key.device
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		result = append(result, sample)
+	}
+	return result
+}
+
+// readFile 读取 JSONL 文件中满足查询条件的记录，文件不存在时返回空，无法解析的行（如写入中断）会被跳过
+func readFile(path string, q Query) ([]Sample, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var samples []Sample
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var sample Sample
+		if err := json.Unmarshal(scanner.Bytes(), &sample); err != nil || !q.match(sample) {
+			continue
+		}
+		samples = append(samples, sample)
+	}
+	return samples, scanner.Err()
+}
+
+// writeFile 每行一个样本地替换整个文件
+func writeFile(path string, samples []Sample) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, sample := range samples {
+		if err := enc.Encode(sample); err != nil {
+			return err
+		}
+	}
+	return state.WriteFile(path, buf.Bytes())
+}
@@ -0,0 +1,228 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordThrottle(t *testing.T) {
+	store := NewStore(t.TempDir())
+	now := time.Now()
+
+	if err := store.Record([]Sample{{Time: now, Device: "Trackpad", Percent: 80}}); err != nil {
+		t.Fatalf("记录失败: %v", err)
+	}
+	// 间隔不足，应该跳过
+	if err := store.Record([]Sample{{Time: now.Add(10 * time.Second), Device: "Trackpad", Percent: 79}}); err != nil {
+		t.Fatalf("记录失败: %v", err)
+	}
+
+	samples, err := store.Query(Query{})
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	if len(samples) != 1 {
+		t.Fatalf("应该只记录 1 条，实际 %d 条", len(samples))
+	}
+
+	// 将上次记录时间回拨到最小间隔之前
+	past := now.Add(-2 * store.MinInterval)
+	if err := os.Chtimes(filepath.Join(store.Dir, ActiveFile), past, past); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Record([]Sample{{Time: now.Add(time.Second), Device: "Trackpad", Percent: 79}}); err != nil {
+		t.Fatalf("记录失败: %v", err)
+	}
+
+	samples, _ = store.Query(Query{})
+	if len(samples) != 2 {
+		t.Errorf("超过最小间隔后应该记录，实际 %d 条", len(samples))
+	}
+}
+
+func TestQuery(t *testing.T) {
+	store := NewStore(t.TempDir())
+	store.MinInterval = 0
+	base := time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 4; i++ {
+		err := store.Record([]Sample{
+			{Time: base.Add(time.Duration(i) * time.Hour), Device: "Magic Trackpad", Serial: "CC01", Percent: 90 - i},
+			{Time: base.Add(time.Duration(i) * time.Hour), Device: "Magic Mouse", Serial: "MM01", Percent: 50 - i},
+		})
+		if err != nil {
+			t.Fatalf("记录失败: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []int
+	}{
+		{"按名称", Query{Device: "magic trackpad"}, []int{90, 89, 88, 87}},
+		{"按序列号", Query{Device: "MM01"}, []int{50, 49, 48, 47}},
+		{"时间范围", Query{Device: "CC01", Since: base.Add(time.Hour), Until: base.Add(2 * time.Hour)}, []int{89, 88}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples, err := store.Query(tt.query)
+			if err != nil {
+				t.Fatalf("查询失败: %v", err)
+			}
+			var got []int
+			for _, s := range samples {
+				got = append(got, s.Percent)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("结果错误: 期望 %v, 实际 %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("结果错误: 期望 %v, 实际 %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCompact(t *testing.T) {
+	store := NewStore(t.TempDir())
+	store.MinInterval = 0
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	var samples []Sample
+	// 超过保留期限，应该删除
+	samples = append(samples, Sample{Time: now.Add(-40 * 24 * time.Hour), Device: "Trackpad", Percent: 10})
+	// 两天前每分钟一条，压缩后每 10 分钟保留一条
+	start := now.Add(-48 * time.Hour).Truncate(store.CompactBucket)
+	for i := 0; i < 30; i++ {
+		samples = append(samples, Sample{Time: start.Add(time.Duration(i) * time.Minute), Device: "Trackpad", Percent: 50})
+	}
+	// 近期记录全部保留，重复记录去重
+	recent := Sample{Time: now.Add(-time.Hour), Device: "Trackpad", Percent: 90}
+	samples = append(samples, recent, recent, Sample{Time: now.Add(-time.Minute), Device: "Trackpad", Percent: 89})
+
+	if err := store.Record(samples); err != nil {
+		t.Fatalf("记录失败: %v", err)
+	}
+	if err := store.Compact(now); err != nil {
+		t.Fatalf("压缩失败: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(store.Dir, ActiveFile)); !os.IsNotExist(err) {
+		t.Errorf("压缩后活动文件应该被删除")
+	}
+
+	got, err := store.Query(Query{})
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	if len(got) != 5 {
+		t.Fatalf("压缩后应该剩余 5 条，实际 %d 条: %+v", len(got), got)
+	}
+	if !got[0].Time.Equal(start) || got[0].Percent != 50 {
+		t.Errorf("降采样应该保留每个时间桶的第一条: %+v", got[0])
+	}
+}
+
+func TestCompactKeepsConcurrentAppends(t *testing.T) {
+	store := NewStore(t.TempDir())
+	store.MinInterval = 0
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// 上次压缩中途失败留下的文件
+	leftover := []Sample{{Time: now.Add(-2 * time.Hour), Device: "Trackpad", Percent: 70}}
+	if err := writeFile(filepath.Join(store.Dir, compactingFile), leftover); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Record([]Sample{{Time: now.Add(-time.Hour), Device: "Trackpad", Percent: 60}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 已有待合并的文件时不移动活动文件，活动文件留到下次压缩
+	if err := store.Compact(now); err != nil {
+		t.Fatalf("压缩失败: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(store.Dir, ActiveFile)); err != nil {
+		t.Errorf("活动文件不应该被删除: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(store.Dir, compactingFile)); !os.IsNotExist(err) {
+		t.Errorf("合并后应该删除待合并的文件")
+	}
+
+	if err := store.Compact(now); err != nil {
+		t.Fatalf("压缩失败: %v", err)
+	}
+	got, _ := store.Query(Query{})
+	if len(got) != 2 || got[0].Percent != 70 || got[1].Percent != 60 {
+		t.Errorf("压缩不应该丢失记录: %+v", got)
+	}
+}
+
+func TestSeries(t *testing.T) {
+	store := NewStore(t.TempDir())
+	store.MinInterval = 0
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		err := store.Record([]Sample{{Time: now.Add(time.Duration(i-5) * time.Minute), Device: "Trackpad", Percent: 80 - i}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	values, err := store.Series("Trackpad", 3, now.Add(-time.Hour))
+	if err != nil || len(values) != 3 || values[0] != 78 || values[2] != 76 {
+		t.Fatalf("Series() = %v, %v", values, err)
+	}
+	values, _ = store.Series("Trackpad", 10, now.Add(-3*time.Minute))
+	if len(values) != 3 || values[0] != 78 {
+		t.Errorf("Series() 应该只返回 since 之后的记录: %v", values)
+	}
+
+	// 无法读取的归档：活动文件中的记录足够或者归档早于 since 时不应该读取
+	archive := filepath.Join(store.Dir, ArchiveFile)
+	if err := os.Mkdir(archive, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Series("Trackpad", 5, time.Time{}); err != nil {
+		t.Errorf("活动文件中已有足够的记录，不应该读取归档: %v", err)
+	}
+	old := now.Add(-2 * time.Hour)
+	if err := os.Chtimes(archive, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Series("Trackpad", 10, now.Add(-time.Hour)); err != nil {
+		t.Errorf("归档早于 since，不应该读取: %v", err)
+	}
+	if _, err := store.Series("Trackpad", 10, time.Time{}); err == nil {
+		t.Errorf("需要读取归档时应该返回错误")
+	}
+}
+
+func TestRecordRotate(t *testing.T) {
+	store := NewStore(t.TempDir())
+	store.MinInterval = 0
+	store.MaxSize = 200
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		err := store.Record([]Sample{{Time: now.Add(time.Duration(i) * time.Second), Device: "Trackpad", Percent: 80 - i}})
+		if err != nil {
+			t.Fatalf("记录失败: %v", err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(store.Dir, ArchiveFile)); err != nil {
+		t.Errorf("超过大小限制后应该轮转到归档文件: %v", err)
+	}
+
+	got, _ := store.Query(Query{})
+	if len(got) != 5 {
+		t.Errorf("轮转不应该丢失记录，实际 %d 条", len(got))
+	}
+}
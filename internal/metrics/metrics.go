@@ -0,0 +1,140 @@
+// Package metrics 以 Prometheus 文本格式导出设备电池和系统指标
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+	"github.com/akayj/tmux-touchpad-battery/internal/snapshot"
+	"github.com/akayj/tmux-touchpad-battery/internal/state"
+)
+
+// labelEscaper 转义标签值中的特殊字符
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Write 将采样结果按 Prometheus 文本格式输出
+func Write(w io.Writer, s *snapshot.Snapshot) error {
+	var b strings.Builder
+
+	var devices []*battery.BatteryInfo
+	for _, d := range s.Devices {
+		if d.Available {
+			devices = append(devices, d)
+		}
+	}
+	labels := deviceLabels(devices)
+
+	writeHeader(&b, "tpb_device_battery_percent", "Peripheral battery level in percent.")
+	for i, d := range devices {
+		fmt.Fprintf(&b, "tpb_device_battery_percent{%s} %d\n", labels[i], d.Percentage)
+	}
+
+	writeHeader(&b, "tpb_device_charging", "Whether the peripheral is charging (1) or not (0).")
+	for i, d := range devices {
+		fmt.Fprintf(&b, "tpb_device_charging{%s} %d\n", labels[i], boolValue(d.IsCharging))
+	}
+
+	if s.SystemErr == nil && s.System.Available {
+		writeHeader(&b, "tpb_cpu_usage_percent", "CPU usage (user + sys) in percent.")
+		fmt.Fprintf(&b, "tpb_cpu_usage_percent %g\n", s.System.CPUUsage)
+
+		if s.System.GPUErr == nil {
+			writeHeader(&b, "tpb_gpu_usage_percent", "GPU usage in percent.")
+			fmt.Fprintf(&b, "tpb_gpu_usage_percent %g\n", s.System.GPUUsage)
+		}
+	}
+
+	writeHeader(&b, "tpb_sample_timestamp_seconds", "Unix time of the sample.")
+	fmt.Fprintf(&b, "tpb_sample_timestamp_seconds %d\n", s.Time.Unix())
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeHeader 输出指标的 HELP 和 TYPE 行
+func writeHeader(b *strings.Builder, name, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+}
+
+// deviceLabels 返回每个设备的标签
+// 没有序列号时使用蓝牙地址区分同名设备；仍然相同时从第二个起加上 index 标签，避免输出重复的时间序列
+func deviceLabels(devices []*battery.BatteryInfo) []string {
+	result := make([]string, len(devices))
+	count := make(map[string]int)
+	for i, d := range devices {
+		labels := fmt.Sprintf(`device="%s"`, labelEscaper.Replace(d.Name))
+		switch {
+		case d.Serial != "":
+			labels += fmt.Sprintf(`,serial="%s"`, labelEscaper.Replace(d.Serial))
+		case d.Address != "":
+			labels += fmt.Sprintf(`,address="%s"`, labelEscaper.Replace(d.Address))
+		}
+
+		count[labels]++
+		if n := count[labels]; n > 1 {
+			labels += fmt.Sprintf(`,index="%d"`, n)
+		}
+		result[i] = labels
+	}
+	return result
+}
+
+func boolValue(v bool) int {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// Exporter 保存最近一次采样，并通过 HTTP 提供 /metrics
+type Exporter struct {
+	mu     sync.RWMutex
+	latest *snapshot.Snapshot
+}
+
+// NewExporter 创建新的导出器
+func NewExporter() *Exporter {
+	return &Exporter{}
+}
+
+// Handle 实现 daemon.Sink，记录最近一次采样
+func (e *Exporter) Handle(s *snapshot.Snapshot) error {
+	e.mu.Lock()
+	e.latest = s
+	e.mu.Unlock()
+	return nil
+}
+
+// ServeHTTP 输出最近一次采样的指标，尚未采样时返回 503
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.RLock()
+	s := e.latest
+	e.mu.RUnlock()
+
+	if s == nil {
+		http.Error(w, "no sample yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = Write(w, s)
+}
+
+// TextfileWriter 将指标写入 node_exporter textfile collector 目录下的文件
+type TextfileWriter struct {
+	Path string
+}
+
+// Handle 实现 daemon.Sink，原子地替换文件，避免 node_exporter 读到不完整的内容
+func (t *TextfileWriter) Handle(s *snapshot.Snapshot) error {
+	var buf bytes.Buffer
+	if err := Write(&buf, s); err != nil {
+		return err
+	}
+	return state.WriteFile(t.Path, buf.Bytes())
+}
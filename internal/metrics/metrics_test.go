@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+	"github.com/akayj/tmux-touchpad-battery/internal/snapshot"
+	"github.com/akayj/tmux-touchpad-battery/internal/snapshot/snapshottest"
+	"github.com/akayj/tmux-touchpad-battery/internal/system"
+)
+
+func TestWrite(t *testing.T) {
+	s := snapshottest.New()
+	s.Devices = append(s.Devices, &battery.BatteryInfo{Name: `Bob's "Mouse"`, Percentage: 7, Available: true})
+	s.System.GPUUsage, s.System.GPUErr = 3, nil
+
+	var buf bytes.Buffer
+	if err := Write(&buf, s); err != nil {
+		t.Fatalf("输出指标失败: %v", err)
+	}
+
+	for _, want := range []string{
+		"# TYPE tpb_device_battery_percent gauge\n",
+		`tpb_device_battery_percent{device="Magic Trackpad",serial="CC01"} 42` + "\n",
+		`tpb_device_battery_percent{device="Bob's \"Mouse\""} 7` + "\n",
+		`tpb_device_charging{device="Magic Trackpad",serial="CC01"} 1` + "\n",
+		"tpb_cpu_usage_percent 12.5\n",
+		"tpb_gpu_usage_percent 3\n",
+		"tpb_sample_timestamp_seconds 1735787045\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("指标输出缺少 %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestWriteDuplicateDevices(t *testing.T) {
+	s := &snapshot.Snapshot{
+		Time: time.Unix(1700000000, 0),
+		Devices: []*battery.BatteryInfo{
+			{Name: "Keyboard", Address: "AA:BB", Percentage: 80, Available: true},
+			{Name: "Keyboard", Address: "CC:DD", Percentage: 60, Available: true},
+			{Name: "Mouse", Percentage: 50, Available: true},
+			{Name: "Mouse", Percentage: 40, Available: true},
+		},
+		System: &system.SystemInfo{},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, s); err != nil {
+		t.Fatalf("输出指标失败: %v", err)
+	}
+
+	for _, want := range []string{
+		`tpb_device_battery_percent{device="Keyboard",address="AA:BB"} 80` + "\n",
+		`tpb_device_battery_percent{device="Keyboard",address="CC:DD"} 60` + "\n",
+		`tpb_device_battery_percent{device="Mouse"} 50` + "\n",
+		`tpb_device_battery_percent{device="Mouse",index="2"} 40` + "\n",
+		`tpb_device_charging{device="Mouse",index="2"} 0` + "\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("指标输出缺少 %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestExporter(t *testing.T) {
+	exporter := NewExporter()
+
+	rec := httptest.NewRecorder()
+	exporter.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != 503 {
+		t.Errorf("尚未采样时应该返回 503，实际: %d", rec.Code)
+	}
+
+	_ = exporter.Handle(snapshottest.New())
+	rec = httptest.NewRecorder()
+	exporter.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != 200 || !strings.Contains(rec.Body.String(), "tpb_cpu_usage_percent 12.5") {
+		t.Errorf("指标响应错误: %d\n%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestTextfileWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tpb.prom")
+	writer := &TextfileWriter{Path: path}
+
+	if err := writer.Handle(snapshottest.New()); err != nil {
+		t.Fatalf("写入 textfile 失败: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取 textfile 失败: %v", err)
+	}
+	if !strings.Contains(string(data), "tpb_device_battery_percent") {
+		t.Errorf("textfile 内容错误:\n%s", data)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("不应该残留临时文件: %v", entries)
+	}
+}
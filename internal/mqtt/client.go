@@ -0,0 +1,178 @@
+// Package mqtt 实现发布电池和系统指标所需的最小 MQTT 3.1.1 客户端（只支持 QoS 0 发布），
+// 并按 Home Assistant MQTT discovery 的约定发布设备配置
+package mqtt
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 默认参数
+const (
+	DefaultPort    = "1883"
+	DefaultTLSPort = "8883"
+	DefaultTimeout = 10 * time.Second
+)
+
+// CONNACK 返回码对应的错误信息
+var connackErrors = map[byte]string{
+	1: "不支持的协议版本",
+	2: "客户端标识被拒绝",
+	3: "服务不可用",
+	4: "用户名或密码错误",
+	5: "未授权",
+}
+
+// Message 一条发布的消息
+type Message struct {
+	Topic   string
+	Payload []byte
+	Retain  bool
+}
+
+// Options 连接参数
+type Options struct {
+	ClientID string
+	Username string
+	Password string
+	// KeepAlive 为 0 时不启用心跳；客户端不发送 PINGREQ，调用方需要在该时间内发布消息
+	KeepAlive time.Duration
+	// Will 连接异常断开时由 broker 发布的遗嘱消息
+	Will *Message
+	// Timeout 建立连接和每次写入的超时
+	Timeout time.Duration
+}
+
+// Client MQTT 客户端连接
+type Client struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+
+	mu sync.Mutex
+}
+
+// Dial 连接 broker 并完成 CONNECT 握手
+// addr 可以是 host:port，或 tcp://、mqtt://、ssl://、mqtts:// 开头的 URL
+func Dial(addr string, opts Options) (*Client, error) {
+	network, hostport, useTLS, err := parseAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	if useTLS {
+		host, _, _ := net.SplitHostPort(hostport)
+		conn, err = tls.DialWithDialer(dialer, network, hostport, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.Dial(network, hostport)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{conn: conn, r: bufio.NewReader(conn), timeout: timeout}
+	if err := c.connect(opts); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// parseAddr 解析 broker 地址，省略端口时使用默认端口
+func parseAddr(addr string) (network, hostport string, useTLS bool, err error) {
+	hostport = addr
+	if strings.Contains(addr, "://") {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return "", "", false, fmt.Errorf("无效的 MQTT 地址 %q: %w", addr, err)
+		}
+		switch u.Scheme {
+		case "tcp", "mqtt":
+		case "ssl", "tls", "mqtts":
+			useTLS = true
+		default:
+			return "", "", false, fmt.Errorf("不支持的 MQTT 协议: %q", u.Scheme)
+		}
+		hostport = u.Host
+	}
+	if hostport == "" {
+		return "", "", false, fmt.Errorf("无效的 MQTT 地址: %q", addr)
+	}
+
+	if _, _, err := net.SplitHostPort(hostport); err != nil {
+		port := DefaultPort
+		if useTLS {
+			port = DefaultTLSPort
+		}
+		hostport = net.JoinHostPort(strings.Trim(hostport, "[]"), port)
+	}
+	return "tcp", hostport, useTLS, nil
+}
+
+// connect 发送 CONNECT 并等待 CONNACK
+func (c *Client) connect(opts Options) error {
+	if err := c.write(connectPacket(opts)); err != nil {
+		return err
+	}
+
+	_ = c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	p, err := readPacket(c.r)
+	if err != nil {
+		return fmt.Errorf("mqtt: 读取 CONNACK 失败: %w", err)
+	}
+	_ = c.conn.SetReadDeadline(time.Time{})
+
+	if p.typ != packetConnack || len(p.body) != 2 {
+		return fmt.Errorf("mqtt: 期望 CONNACK，收到类型 %d", p.typ)
+	}
+	if rc := p.body[1]; rc != 0 {
+		msg, ok := connackErrors[rc]
+		if !ok {
+			msg = fmt.Sprintf("返回码 %d", rc)
+		}
+		return fmt.Errorf("mqtt: 连接被拒绝: %s", msg)
+	}
+	return nil
+}
+
+// Publish 以 QoS 0 发布一条消息
+func (c *Client) Publish(m Message) error {
+	if m.Topic == "" || strings.ContainsAny(m.Topic, "+#") {
+		return fmt.Errorf("mqtt: 无效的主题 %q", m.Topic)
+	}
+	return c.write(publishPacket(m))
+}
+
+// Close 发送 DISCONNECT 后关闭连接，正常断开时 broker 不会发布遗嘱消息
+func (c *Client) Close() error {
+	err := c.write(packet{typ: packetDisconnect})
+	return errors.Join(err, c.conn.Close())
+}
+
+// write 发送一个报文
+func (c *Client) write(p packet) error {
+	data, err := p.encode()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	_, err = c.conn.Write(data)
+	return err
+}
@@ -0,0 +1,116 @@
+package mqtt
+
+import (
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+)
+
+// discoveryConfig Home Assistant MQTT discovery 实体配置
+type discoveryConfig struct {
+	Name              string          `json:"name"`
+	UniqueID          string          `json:"unique_id"`
+	StateTopic        string          `json:"state_topic"`
+	ValueTemplate     string          `json:"value_template"`
+	AvailabilityTopic string          `json:"availability_topic"`
+	DeviceClass       string          `json:"device_class,omitempty"`
+	StateClass        string          `json:"state_class,omitempty"`
+	Unit              string          `json:"unit_of_measurement,omitempty"`
+	Icon              string          `json:"icon,omitempty"`
+	Device            discoveryDevice `json:"device"`
+}
+
+// discoveryDevice Home Assistant 设备信息，同一设备的实体归为一组
+type discoveryDevice struct {
+	Identifiers []string `json:"identifiers"`
+	Name        string   `json:"name"`
+	ViaDevice   string   `json:"via_device,omitempty"`
+}
+
+// hostDevice 返回本机在 Home Assistant 中的设备信息
+func (p *Publisher) hostDevice() discoveryDevice {
+	return discoveryDevice{
+		Identifiers: []string{"tpb_" + p.Node},
+		Name:        p.Node,
+	}
+}
+
+// announce 发布一个实体的 discovery 配置，同一连接上只发布一次
+func (p *Publisher) announce(component, objectID string, cfg discoveryConfig) error {
+	if p.DiscoveryPrefix == "" {
+		return nil
+	}
+	topic := p.DiscoveryPrefix + "/" + component + "/" + objectID + "/config"
+	if p.announced[topic] {
+		return nil
+	}
+
+	cfg.UniqueID = objectID
+	cfg.AvailabilityTopic = p.statusTopic()
+	if err := p.publishJSON(topic, cfg); err != nil {
+		return err
+	}
+	p.announced[topic] = true
+	return nil
+}
+
+// announceDevice 发布外设的电量传感器和充电状态二元传感器
+func (p *Publisher) announceDevice(id string, d *battery.BatteryInfo) error {
+	name := d.Name
+	if name == "" {
+		name = "Touchpad"
+	}
+	device := discoveryDevice{
+		Identifiers: []string{"tpb_" + p.Node + "_" + id},
+		Name:        name,
+		ViaDevice:   "tpb_" + p.Node,
+	}
+	base := "tpb_" + p.Node + "_" + id
+
+	err := p.announce("sensor", base+"_battery", discoveryConfig{
+		Name:          "Battery",
+		StateTopic:    p.stateTopic(id),
+		ValueTemplate: "{{ value_json.percentage }}",
+		DeviceClass:   "battery",
+		StateClass:    "measurement",
+		Unit:          "%",
+		Device:        device,
+	})
+	if err != nil {
+		return err
+	}
+
+	return p.announce("binary_sensor", base+"_charging", discoveryConfig{
+		Name:          "Charging",
+		StateTopic:    p.stateTopic(id),
+		ValueTemplate: "{{ 'ON' if value_json.charging else 'OFF' }}",
+		DeviceClass:   "battery_charging",
+		Device:        device,
+	})
+}
+
+// announceSystem 发布本机的 CPU（以及可用时的 GPU）使用率传感器
+func (p *Publisher) announceSystem(gpu bool) error {
+	base := "tpb_" + p.Node
+
+	err := p.announce("sensor", base+"_cpu", discoveryConfig{
+		Name:          "CPU usage",
+		StateTopic:    p.stateTopic("system"),
+		ValueTemplate: "{{ value_json.cpu_usage_percent }}",
+		StateClass:    "measurement",
+		Unit:          "%",
+		Icon:          "mdi:cpu-64-bit",
+		Device:        p.hostDevice(),
+	})
+	if err != nil || !gpu {
+		return err
+	}
+
+	return p.announce("sensor", base+"_gpu", discoveryConfig{
+		Name:          "GPU usage",
+		StateTopic:    p.stateTopic("system"),
+		ValueTemplate: "{{ value_json.gpu_usage_percent }}",
+		StateClass:    "measurement",
+		Unit:          "%",
+		Icon:          "mdi:expansion-card",
+		Device:        p.hostDevice(),
+	})
+}
@@ -0,0 +1,363 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/snapshot/snapshottest"
+)
+
+// connectInfo broker 收到的 CONNECT 报文内容
+type connectInfo struct {
+	ClientID  string
+	Username  string
+	Password  string
+	KeepAlive uint16
+	Will      *Message
+}
+
+// broker 进程内的 MQTT broker 替身，记录 CONNECT 和 PUBLISH 报文，
+// 连接异常断开时按 MQTT 的约定发布遗嘱消息
+type broker struct {
+	ln net.Listener
+	// code CONNACK 返回码
+	code byte
+
+	mu       sync.Mutex
+	connects []connectInfo
+	messages []Message
+	conns    []net.Conn
+	changed  chan struct{}
+}
+
+func newBroker(t *testing.T) *broker {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &broker{ln: ln, changed: make(chan struct{}, 1)}
+	go b.serve()
+	t.Cleanup(func() {
+		ln.Close()
+		b.dropAll()
+	})
+	return b
+}
+
+func (b *broker) addr() string {
+	return b.ln.Addr().String()
+}
+
+func (b *broker) serve() {
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		b.mu.Lock()
+		b.conns = append(b.conns, conn)
+		b.mu.Unlock()
+		go b.handle(conn)
+	}
+}
+
+func (b *broker) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	var will *Message
+	for {
+		p, err := readPacket(r)
+		if err != nil {
+			// 没有收到 DISCONNECT 就断开，发布遗嘱
+			if will != nil {
+				b.record(*will)
+			}
+			return
+		}
+
+		switch p.typ {
+		case packetConnect:
+			info, err := parseConnect(p)
+			if err != nil {
+				return
+			}
+			b.mu.Lock()
+			b.connects = append(b.connects, info)
+			b.mu.Unlock()
+			will = info.Will
+			_, _ = conn.Write([]byte{packetConnack << 4, 2, 0, b.code})
+			if b.code != 0 {
+				return
+			}
+		case packetPublish:
+			topic, payload, err := readString(p.body)
+			if err != nil {
+				return
+			}
+			b.record(Message{Topic: topic, Payload: payload, Retain: p.flags&0x01 != 0})
+		case packetDisconnect:
+			return
+		}
+	}
+}
+
+func (b *broker) record(m Message) {
+	b.mu.Lock()
+	b.messages = append(b.messages, m)
+	b.mu.Unlock()
+	select {
+	case b.changed <- struct{}{}:
+	default:
+	}
+}
+
+// dropAll 模拟网络中断，关闭所有连接
+func (b *broker) dropAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, c := range b.conns {
+		c.Close()
+	}
+	b.conns = nil
+}
+
+// wait 等待 broker 收到满足条件的消息
+func (b *broker) wait(t *testing.T, ok func([]Message) bool) []Message {
+	t.Helper()
+	deadline := time.After(2 * time.Second)
+	for {
+		b.mu.Lock()
+		msgs := append([]Message(nil), b.messages...)
+		b.mu.Unlock()
+		if ok(msgs) {
+			return msgs
+		}
+		select {
+		case <-b.changed:
+		case <-deadline:
+			t.Fatalf("等待消息超时, 已收到 %d 条", len(msgs))
+		}
+	}
+}
+
+// retained 返回每个主题最后一条保留消息
+func retained(msgs []Message) map[string]string {
+	m := make(map[string]string)
+	for _, msg := range msgs {
+		if msg.Retain {
+			m[msg.Topic] = string(msg.Payload)
+		}
+	}
+	return m
+}
+
+func parseConnect(p packet) (connectInfo, error) {
+	name, rest, err := readString(p.body)
+	if err != nil || name != "MQTT" || len(rest) < 4 || rest[0] != 4 {
+		return connectInfo{}, errors.New("bad connect")
+	}
+	flags := rest[1]
+	info := connectInfo{KeepAlive: binary.BigEndian.Uint16(rest[2:4])}
+	rest = rest[4:]
+
+	if info.ClientID, rest, err = readString(rest); err != nil {
+		return info, err
+	}
+	if flags&flagWill != 0 {
+		var topic, payload string
+		if topic, rest, err = readString(rest); err != nil {
+			return info, err
+		}
+		if payload, rest, err = readString(rest); err != nil {
+			return info, err
+		}
+		info.Will = &Message{Topic: topic, Payload: []byte(payload), Retain: flags&flagWillRetain != 0}
+	}
+	if flags&flagUsername != 0 {
+		if info.Username, rest, err = readString(rest); err != nil {
+			return info, err
+		}
+	}
+	if flags&flagPassword != 0 {
+		if info.Password, _, err = readString(rest); err != nil {
+			return info, err
+		}
+	}
+	return info, nil
+}
+
+func newTestPublisher(b *broker) *Publisher {
+	p := NewPublisher(b.addr(), Options{Username: "user", Password: "secret", KeepAlive: time.Minute})
+	p.Node = "mac"
+	return p
+}
+
+func TestRemainingLength(t *testing.T) {
+	for _, n := range []int{0, 127, 128, 16383, 16384, 2097151, 2097152} {
+		data, err := packet{typ: packetPublish, body: make([]byte, n)}.encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := readPacket(bufio.NewReader(bytes.NewReader(data)))
+		if err != nil {
+			t.Fatalf("n=%d: %v", n, err)
+		}
+		if len(p.body) != n || p.typ != packetPublish {
+			t.Errorf("n=%d: 解析得到 %d 字节, 类型 %d", n, len(p.body), p.typ)
+		}
+	}
+}
+
+func TestParseAddr(t *testing.T) {
+	tests := []struct {
+		in, hostport string
+		tls          bool
+	}{
+		{"localhost", "localhost:1883", false},
+		{"10.0.0.2:1884", "10.0.0.2:1884", false},
+		{"tcp://broker", "broker:1883", false},
+		{"mqtts://broker", "broker:8883", true},
+		{"ssl://broker:9999", "broker:9999", true},
+	}
+	for _, tt := range tests {
+		_, hostport, useTLS, err := parseAddr(tt.in)
+		if err != nil || hostport != tt.hostport || useTLS != tt.tls {
+			t.Errorf("parseAddr(%q) = %q, %v, %v", tt.in, hostport, useTLS, err)
+		}
+	}
+	if _, _, _, err := parseAddr("ws://broker"); err == nil {
+		t.Error("不支持的协议应返回错误")
+	}
+}
+
+func TestConnectRefused(t *testing.T) {
+	b := newBroker(t)
+	b.code = 5
+	if _, err := Dial(b.addr(), Options{ClientID: "x"}); err == nil {
+		t.Fatal("连接被拒绝时应返回错误")
+	}
+}
+
+func TestPublisher(t *testing.T) {
+	b := newBroker(t)
+	p := newTestPublisher(b)
+
+	if err := p.Handle(snapshottest.New()); err != nil {
+		t.Fatal(err)
+	}
+	msgs := b.wait(t, func(m []Message) bool {
+		_, ok := retained(m)["tpb/mac/system/state"]
+		return ok
+	})
+
+	b.mu.Lock()
+	info := b.connects[0]
+	b.mu.Unlock()
+	if !strings.HasPrefix(info.ClientID, "tmux-touchpad-battery-") {
+		t.Errorf("client id = %q", info.ClientID)
+	}
+	if info.Username != "user" || info.Password != "secret" || info.KeepAlive != 60 {
+		t.Errorf("connect = %+v", info)
+	}
+	if info.Will == nil || info.Will.Topic != "tpb/mac/status" || string(info.Will.Payload) != StatusOffline || !info.Will.Retain {
+		t.Errorf("will = %+v", info.Will)
+	}
+
+	topics := retained(msgs)
+	if topics["tpb/mac/status"] != StatusOnline {
+		t.Errorf("status = %q", topics["tpb/mac/status"])
+	}
+
+	var st deviceState
+	if err := json.Unmarshal([]byte(topics["tpb/mac/cc01/state"]), &st); err != nil {
+		t.Fatalf("device state: %v (%v)", err, topics)
+	}
+	if st.Percentage != 42 || !st.Charging || st.Name != "Magic Trackpad" {
+		t.Errorf("device state = %+v", st)
+	}
+	if got := topics["tpb/mac/system/state"]; got != `{"cpu_usage_percent":12.5}` {
+		t.Errorf("system state = %s", got)
+	}
+
+	var cfg discoveryConfig
+	if err := json.Unmarshal([]byte(topics["homeassistant/sensor/tpb_mac_cc01_battery/config"]), &cfg); err != nil {
+		t.Fatalf("discovery: %v", err)
+	}
+	if cfg.StateTopic != "tpb/mac/cc01/state" || cfg.DeviceClass != "battery" || cfg.Unit != "%" ||
+		cfg.AvailabilityTopic != "tpb/mac/status" || cfg.Device.Name != "Magic Trackpad" {
+		t.Errorf("discovery = %+v", cfg)
+	}
+	if _, ok := topics["homeassistant/binary_sensor/tpb_mac_cc01_charging/config"]; !ok {
+		t.Error("缺少充电状态的 discovery 配置")
+	}
+	if _, ok := topics["homeassistant/sensor/tpb_mac_gpu/config"]; ok {
+		t.Error("GPU 不可用时不应发布 GPU 传感器")
+	}
+
+	// 同一连接上不重复发布 discovery 配置
+	count := len(msgs)
+	next := snapshottest.New()
+	next.Devices[0].Percentage = 41
+	if err := p.Handle(next); err != nil {
+		t.Fatal(err)
+	}
+	msgs = b.wait(t, func(m []Message) bool { return len(m) >= count+2 })
+	for _, m := range msgs[count:] {
+		if strings.HasPrefix(m.Topic, DefaultDiscoveryPrefix+"/") {
+			t.Errorf("重复发布 discovery: %s", m.Topic)
+		}
+	}
+
+	// 正常关闭时发布 offline，不触发遗嘱
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b.wait(t, func(m []Message) bool { return retained(m)["tpb/mac/status"] == StatusOffline })
+}
+
+func TestPublisherReconnect(t *testing.T) {
+	b := newBroker(t)
+	p := newTestPublisher(b)
+	p.DiscoveryPrefix = ""
+
+	if err := p.Handle(snapshottest.New()); err != nil {
+		t.Fatal(err)
+	}
+	b.wait(t, func(m []Message) bool { return len(m) >= 3 })
+
+	// 连接中断后 broker 发布遗嘱，客户端在写入失败后重新连接
+	b.dropAll()
+	b.wait(t, func(m []Message) bool { return retained(m)["tpb/mac/status"] == StatusOffline })
+
+	next := snapshottest.New()
+	next.Devices[0].Percentage = 40
+	var err error
+	for i := 0; i < 5; i++ {
+		if err = p.Handle(next); err == nil {
+			b.mu.Lock()
+			n := len(b.connects)
+			b.mu.Unlock()
+			if n >= 2 {
+				break
+			}
+		}
+	}
+	if err != nil {
+		t.Fatalf("重新连接失败: %v", err)
+	}
+	b.wait(t, func(m []Message) bool {
+		var st deviceState
+		_ = json.Unmarshal([]byte(retained(m)["tpb/mac/cc01/state"]), &st)
+		return retained(m)["tpb/mac/status"] == StatusOnline && st.Percentage == 40 && st.Charging
+	})
+}
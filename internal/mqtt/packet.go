@@ -0,0 +1,159 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// 控制报文类型（固定报头高 4 位）
+const (
+	packetConnect    byte = 1
+	packetConnack    byte = 2
+	packetPublish    byte = 3
+	packetDisconnect byte = 14
+)
+
+// CONNECT 报文的连接标志
+const (
+	flagCleanSession byte = 0x02
+	flagWill         byte = 0x04
+	flagWillRetain   byte = 0x20
+	flagPassword     byte = 0x40
+	flagUsername     byte = 0x80
+)
+
+// maxRemainingLength 剩余长度字段可以表示的最大值
+const maxRemainingLength = 268435455
+
+// errMalformed 报文格式错误
+var errMalformed = errors.New("mqtt: 报文格式错误")
+
+// packet 一个控制报文
+type packet struct {
+	typ   byte
+	flags byte
+	body  []byte
+}
+
+// encode 生成带固定报头的报文
+func (p packet) encode() ([]byte, error) {
+	if len(p.body) > maxRemainingLength {
+		return nil, fmt.Errorf("mqtt: 报文过长 (%d 字节)", len(p.body))
+	}
+
+	buf := []byte{p.typ<<4 | p.flags&0x0f}
+	n := len(p.body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(buf, p.body...), nil
+}
+
+// readPacket 读取一个控制报文
+func readPacket(r *bufio.Reader) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return packet{}, errMalformed
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		length += int(b&0x7f) * multiplier
+		multiplier *= 128
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+	return packet{typ: header >> 4, flags: header & 0x0f, body: body}, nil
+}
+
+// appendString 追加带 2 字节长度前缀的 UTF-8 字符串
+func appendString(buf []byte, s string) []byte {
+	return appendBytes(buf, []byte(s))
+}
+
+// appendBytes 追加带 2 字节长度前缀的二进制数据
+func appendBytes(buf, data []byte) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(data)))
+	return append(buf, data...)
+}
+
+// readString 读取带 2 字节长度前缀的字符串，返回剩余的数据
+func readString(data []byte) (string, []byte, error) {
+	if len(data) < 2 {
+		return "", nil, errMalformed
+	}
+	n := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+n {
+		return "", nil, errMalformed
+	}
+	return string(data[2 : 2+n]), data[2+n:], nil
+}
+
+// connectPacket 生成 CONNECT 报文
+func connectPacket(opts Options) packet {
+	flags := flagCleanSession
+	if opts.Will != nil {
+		flags |= flagWill
+		if opts.Will.Retain {
+			flags |= flagWillRetain
+		}
+	}
+	if opts.Username != "" {
+		flags |= flagUsername
+		if opts.Password != "" {
+			flags |= flagPassword
+		}
+	}
+
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(opts.KeepAlive.Seconds()))
+
+	body = appendString(body, opts.ClientID)
+	if opts.Will != nil {
+		body = appendString(body, opts.Will.Topic)
+		body = appendBytes(body, opts.Will.Payload)
+	}
+	if opts.Username != "" {
+		body = appendString(body, opts.Username)
+		if opts.Password != "" {
+			body = appendString(body, opts.Password)
+		}
+	}
+	return packet{typ: packetConnect, body: body}
+}
+
+// publishPacket 生成 QoS 0 的 PUBLISH 报文
+func publishPacket(m Message) packet {
+	var flags byte
+	if m.Retain {
+		flags = 0x01
+	}
+	body := appendString(nil, m.Topic)
+	body = append(body, m.Payload...)
+	return packet{typ: packetPublish, flags: flags, body: body}
+}
@@ -0,0 +1,228 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+	"github.com/akayj/tmux-touchpad-battery/internal/snapshot"
+)
+
+// 默认主题前缀
+const (
+	DefaultPrefix          = "tpb"
+	DefaultDiscoveryPrefix = "homeassistant"
+)
+
+// 可用性主题的消息
+const (
+	StatusOnline  = "online"
+	StatusOffline = "offline"
+)
+
+// Publisher 实现 daemon.Sink，将每次采样发布到 MQTT
+//
+// 主题（均为保留消息）：
+//
+//	<prefix>/<node>/status              online / offline（遗嘱消息）
+//	<prefix>/<node>/<device>/state      设备电量 JSON
+//	<prefix>/<node>/system/state        CPU/GPU 使用率 JSON
+//
+// DiscoveryPrefix 不为空时同时发布 Home Assistant discovery 配置
+type Publisher struct {
+	Addr            string
+	Options         Options
+	Prefix          string
+	DiscoveryPrefix string
+	// Node 主机标识，用于区分多台机器
+	Node string
+
+	mu     sync.Mutex
+	client *Client
+	// announced 当前连接上已经发布过 discovery 配置的实体
+	announced map[string]bool
+}
+
+// deviceState 设备状态消息
+type deviceState struct {
+	Name       string `json:"name"`
+	Serial     string `json:"serial,omitempty"`
+	Address    string `json:"address,omitempty"`
+	Percentage int    `json:"percentage"`
+	Charging   bool   `json:"charging"`
+	State      string `json:"state"`
+}
+
+// systemState 系统状态消息
+type systemState struct {
+	CPU float64  `json:"cpu_usage_percent"`
+	GPU *float64 `json:"gpu_usage_percent,omitempty"`
+}
+
+// NewPublisher 创建新的发布器，Node 默认为主机名
+func NewPublisher(addr string, opts Options) *Publisher {
+	node := "localhost"
+	if host, err := os.Hostname(); err == nil {
+		node = host
+	}
+	node = topicID(node)
+
+	if opts.ClientID == "" {
+		opts.ClientID = "tmux-touchpad-battery-" + node
+	}
+
+	return &Publisher{
+		Addr:            addr,
+		Options:         opts,
+		Prefix:          DefaultPrefix,
+		DiscoveryPrefix: DefaultDiscoveryPrefix,
+		Node:            node,
+	}
+}
+
+// statusTopic 返回可用性主题
+func (p *Publisher) statusTopic() string {
+	return p.Prefix + "/" + p.Node + "/status"
+}
+
+// stateTopic 返回设备或系统的状态主题
+func (p *Publisher) stateTopic(id string) string {
+	return p.Prefix + "/" + p.Node + "/" + id + "/state"
+}
+
+// Handle 实现 daemon.Sink，发布失败时断开连接，下次采样时重新连接
+func (p *Publisher) Handle(s *snapshot.Snapshot) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.client == nil {
+		if err := p.connect(); err != nil {
+			return err
+		}
+	}
+
+	if err := p.publish(s); err != nil {
+		p.client.conn.Close()
+		p.client = nil
+		return err
+	}
+	return nil
+}
+
+// connect 连接 broker 并发布 online 状态
+func (p *Publisher) connect() error {
+	opts := p.Options
+	opts.Will = &Message{Topic: p.statusTopic(), Payload: []byte(StatusOffline), Retain: true}
+
+	client, err := Dial(p.Addr, opts)
+	if err != nil {
+		return err
+	}
+	if err := client.Publish(Message{Topic: p.statusTopic(), Payload: []byte(StatusOnline), Retain: true}); err != nil {
+		client.conn.Close()
+		return err
+	}
+
+	p.client = client
+	p.announced = make(map[string]bool)
+	return nil
+}
+
+// publish 发布一次采样中的设备和系统状态
+func (p *Publisher) publish(s *snapshot.Snapshot) error {
+	if s.DevicesErr == nil {
+		for _, d := range s.Devices {
+			if !d.Available {
+				continue
+			}
+			if err := p.publishDevice(d); err != nil {
+				return err
+			}
+		}
+	}
+
+	if s.SystemErr == nil && s.System != nil && s.System.Available {
+		st := systemState{CPU: s.System.CPUUsage}
+		if s.System.GPUErr == nil {
+			gpu := s.System.GPUUsage
+			st.GPU = &gpu
+		}
+		if err := p.announceSystem(st.GPU != nil); err != nil {
+			return err
+		}
+		if err := p.publishJSON(p.stateTopic("system"), st); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishDevice 发布单个设备的状态
+func (p *Publisher) publishDevice(d *battery.BatteryInfo) error {
+	id := deviceID(d)
+	if err := p.announceDevice(id, d); err != nil {
+		return err
+	}
+	return p.publishJSON(p.stateTopic(id), deviceState{
+		Name:       d.Name,
+		Serial:     d.Serial,
+		Address:    d.Address,
+		Percentage: d.Percentage,
+		Charging:   d.IsCharging,
+		State:      d.ChargeState().String(),
+	})
+}
+
+// publishJSON 以保留消息发布 JSON
+func (p *Publisher) publishJSON(topic string, v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return p.client.Publish(Message{Topic: topic, Payload: payload, Retain: true})
+}
+
+// Close 发布 offline 状态并断开连接
+func (p *Publisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.client == nil {
+		return nil
+	}
+	err := p.client.Publish(Message{Topic: p.statusTopic(), Payload: []byte(StatusOffline), Retain: true})
+	err = errors.Join(err, p.client.Close())
+	p.client = nil
+	return err
+}
+
+// deviceID 返回设备在主题中的标识，优先使用序列号
+func deviceID(d *battery.BatteryInfo) string {
+	if d.Serial != "" {
+		return topicID(d.Serial)
+	}
+	if d.Name != "" {
+		return topicID(d.Name)
+	}
+	return "touchpad"
+}
+
+// topicID 将名称转换为只包含小写字母、数字和下划线的标识
+func topicID(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	id := strings.Trim(b.String(), "_")
+	if id == "" {
+		return "unknown"
+	}
+	return id
+}
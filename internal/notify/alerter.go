@@ -0,0 +1,184 @@
+package notify
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+	"github.com/akayj/tmux-touchpad-battery/internal/snapshot"
+)
+
+// 默认参数
+const (
+	DefaultHysteresis = 5
+	DefaultSnooze     = 30 * time.Minute
+)
+
+// Alerter 在设备电量跌破阈值时发送一次通知
+// 电量回升到阈值加回差以上（或开始充电）后重新启用该阈值；
+// 同一设备两次通知之间至少间隔 Snooze，期间跌破的阈值在间隔结束后补发
+type Alerter struct {
+	Notifier Notifier
+
+	// Thresholds 从高到低排列的电量阈值
+	Thresholds []int
+	Hysteresis int
+	Snooze     time.Duration
+	// SnoozePath 设置时每次 Handle 前从该文件读取暂停时间，由 snooze 子命令写入
+	SnoozePath string
+
+	mu      sync.Mutex
+	devices map[string]*deviceState
+	snooze  time.Time
+
+	// dirty 状态自上次加载或保存后是否发生变化
+	dirty bool
+}
+
+// deviceState 单个设备的通知状态
+type deviceState struct {
+	// fired 已经通知过、尚未重新启用的阈值
+	fired map[int]bool
+	// last 上次通知的时间
+	last time.Time
+}
+
+// NewAlerter 创建新的低电量提醒
+func NewAlerter(notifier Notifier, thresholds []int) *Alerter {
+	sorted := append([]int(nil), thresholds...)
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+
+	return &Alerter{
+		Notifier:   notifier,
+		Thresholds: sorted,
+		Hysteresis: DefaultHysteresis,
+		Snooze:     DefaultSnooze,
+		devices:    make(map[string]*deviceState),
+	}
+}
+
+// ParseThresholds 解析逗号分隔的阈值列表，如 "20,10,5"
+func ParseThresholds(s string) ([]int, error) {
+	var thresholds []int
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		v, err := strconv.Atoi(part)
+		if err != nil || v <= 0 || v > 100 {
+			return nil, fmt.Errorf("无效的阈值: %q", part)
+		}
+		thresholds = append(thresholds, v)
+	}
+	if len(thresholds) == 0 {
+		return nil, fmt.Errorf("至少需要一个阈值")
+	}
+	return thresholds, nil
+}
+
+// SnoozeUntil 在指定时间之前暂停所有通知，阈值状态照常更新
+func (a *Alerter) SnoozeUntil(t time.Time) {
+	a.mu.Lock()
+	a.snooze = t
+	a.mu.Unlock()
+}
+
+// Handle 实现 daemon.Sink，检查采样结果中每个设备的电量
+func (a *Alerter) Handle(s *snapshot.Snapshot) error {
+	if a.SnoozePath != "" {
+		if until, err := LoadSnooze(a.SnoozePath); err == nil {
+			a.SnoozeUntil(until)
+		}
+	}
+
+	var errs []error
+	for _, d := range s.Devices {
+		if err := a.Check(d, s.Time); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("发送通知失败: %v", errs)
+	}
+	return nil
+}
+
+// Check 检查单个设备的电量，新跌破阈值时发送通知
+// 一次跌破多个阈值时只按最低的阈值通知一次
+func (a *Alerter) Check(d *battery.BatteryInfo, now time.Time) error {
+	if !d.Available {
+		return nil
+	}
+
+	a.mu.Lock()
+	key := deviceKey(d)
+	st, ok := a.devices[key]
+	if !ok {
+		st = &deviceState{fired: make(map[int]bool)}
+		a.devices[key] = st
+	}
+
+	pending := -1
+	for _, threshold := range a.Thresholds {
+		switch {
+		case d.IsCharging || d.Percentage >= threshold+a.Hysteresis:
+			// 充电或回升到回差以上，重新启用
+			if st.fired[threshold] {
+				delete(st.fired, threshold)
+				a.dirty = true
+			}
+		case d.Percentage < threshold && !st.fired[threshold]:
+			pending = threshold
+		}
+	}
+
+	// 暂停期间不标记为已通知，暂停结束后仍低于阈值时补发
+	send := pending >= 0 &&
+		now.Sub(st.last) >= a.Snooze &&
+		!now.Before(a.snooze)
+	if send {
+		st.last = now
+		a.dirty = true
+		for _, threshold := range a.Thresholds {
+			if d.Percentage < threshold {
+				st.fired[threshold] = true
+			}
+		}
+	}
+	a.mu.Unlock()
+
+	if !send {
+		return nil
+	}
+	return a.Notifier.Notify(a.notification(d, pending))
+}
+
+// notification 生成低电量通知，跌破最低阈值时为紧急通知
+func (a *Alerter) notification(d *battery.BatteryInfo, threshold int) Notification {
+	name := d.Name
+	if name == "" {
+		name = "Touchpad"
+	}
+
+	n := Notification{
+		Title: name + " battery low",
+		Body:  fmt.Sprintf("%s: %d%% remaining", name, d.Percentage),
+	}
+	if threshold == a.Thresholds[len(a.Thresholds)-1] {
+		n.Urgency = UrgencyCritical
+	}
+	return n
+}
+
+// deviceKey 返回设备标识，优先使用序列号
+func deviceKey(d *battery.BatteryInfo) string {
+	if d.Serial != "" {
+		return d.Serial
+	}
+	return d.Name
+}
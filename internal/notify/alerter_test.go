@@ -0,0 +1,211 @@
+package notify
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+	"github.com/akayj/tmux-touchpad-battery/internal/snapshot"
+)
+
+// fakeNotifier 记录收到的通知
+type fakeNotifier struct {
+	sent []Notification
+}
+
+func (f *fakeNotifier) Notify(n Notification) error {
+	f.sent = append(f.sent, n)
+	return nil
+}
+
+// reading 表示一次电量读数
+type reading struct {
+	percent  int
+	charging bool
+}
+
+func TestAlerter(t *testing.T) {
+	tests := []struct {
+		name     string
+		readings []reading
+		snooze   time.Duration
+		want     []int // 每条通知对应的电量
+	}{
+		{
+			name:     "跌破阈值只通知一次",
+			readings: []reading{{25, false}, {20, false}, {19, false}, {18, false}},
+			want:     []int{19},
+		},
+		{
+			name:     "恰好等于阈值不通知",
+			readings: []reading{{21, false}, {20, false}, {20, false}, {10, false}},
+			want:     []int{10},
+		},
+		{
+			name:     "阈值附近抖动不重复通知",
+			readings: []reading{{20, false}, {19, false}, {20, false}, {19, false}, {21, false}, {18, false}},
+			want:     []int{19},
+		},
+		{
+			name:     "回升超过回差后重新启用",
+			readings: []reading{{19, false}, {25, false}, {19, false}},
+			want:     []int{19, 19},
+		},
+		{
+			name:     "充电后重新启用",
+			readings: []reading{{19, false}, {19, true}, {19, false}},
+			want:     []int{19, 19},
+		},
+		{
+			name:     "依次跌破多个阈值",
+			readings: []reading{{19, false}, {15, false}, {9, false}, {4, false}},
+			want:     []int{19, 9, 4},
+		},
+		{
+			name:     "一次跌破多个阈值只通知一次",
+			readings: []reading{{50, false}, {4, false}, {3, false}},
+			want:     []int{4},
+		},
+		{
+			name:     "暂停期间跌破的阈值在暂停结束后补发",
+			readings: []reading{{19, false}, {9, false}, {8, false}, {8, false}},
+			snooze:   2 * time.Minute,
+			want:     []int{19, 8},
+		},
+		{
+			name:     "正在充电不通知",
+			readings: []reading{{5, true}, {4, true}},
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeNotifier{}
+			alerter := NewAlerter(fake, []int{5, 20, 10})
+			alerter.Snooze = tt.snooze
+
+			now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+			for _, r := range tt.readings {
+				info := &battery.BatteryInfo{Name: "Magic Trackpad", Percentage: r.percent, IsCharging: r.charging, Available: true}
+				if err := alerter.Check(info, now); err != nil {
+					t.Fatalf("检查失败: %v", err)
+				}
+				now = now.Add(time.Minute)
+			}
+
+			if len(fake.sent) != len(tt.want) {
+				t.Fatalf("期望 %d 条通知，实际 %d 条: %+v", len(tt.want), len(fake.sent), fake.sent)
+			}
+			for i, percent := range tt.want {
+				if body := fmt.Sprintf("Magic Trackpad: %d%% remaining", percent); fake.sent[i].Body != body {
+					t.Errorf("第 %d 条通知: 期望 %q, 实际 %q", i+1, body, fake.sent[i].Body)
+				}
+			}
+		})
+	}
+}
+
+func TestAlerterNotification(t *testing.T) {
+	fake := &fakeNotifier{}
+	alerter := NewAlerter(fake, []int{20, 5})
+	alerter.Snooze = 0
+
+	now := time.Now()
+	_ = alerter.Check(&battery.BatteryInfo{Name: "Magic Trackpad", Percentage: 18, Available: true}, now)
+	_ = alerter.Check(&battery.BatteryInfo{Name: "Magic Trackpad", Percentage: 4, Available: true}, now)
+
+	if len(fake.sent) != 2 {
+		t.Fatalf("期望 2 条通知，实际 %d 条", len(fake.sent))
+	}
+	if fake.sent[0].Body != "Magic Trackpad: 18% remaining" || fake.sent[0].Urgency != UrgencyNormal {
+		t.Errorf("第一条通知错误: %+v", fake.sent[0])
+	}
+	if fake.sent[1].Urgency != UrgencyCritical {
+		t.Errorf("跌破最低阈值应该是紧急通知: %+v", fake.sent[1])
+	}
+}
+
+func TestAlerterSnoozeUntil(t *testing.T) {
+	fake := &fakeNotifier{}
+	alerter := NewAlerter(fake, []int{20})
+
+	now := time.Now()
+	alerter.SnoozeUntil(now.Add(time.Hour))
+	_ = alerter.Check(&battery.BatteryInfo{Name: "Magic Trackpad", Percentage: 10, Available: true}, now)
+	if len(fake.sent) != 0 {
+		t.Fatalf("暂停期间不应该通知")
+	}
+
+	_ = alerter.Check(&battery.BatteryInfo{Name: "Magic Trackpad", Percentage: 10, Available: true}, now.Add(2*time.Hour))
+	if len(fake.sent) != 1 {
+		t.Errorf("暂停结束后应该补发通知")
+	}
+}
+
+func TestAlerterSnoozePath(t *testing.T) {
+	fake := &fakeNotifier{}
+	alerter := NewAlerter(fake, []int{20})
+	alerter.SnoozePath = filepath.Join(t.TempDir(), SnoozeFile)
+
+	now := time.Now()
+	low := &snapshot.Snapshot{Time: now, Devices: []*battery.BatteryInfo{{Name: "Magic Trackpad", Percentage: 10, Available: true}}}
+
+	if err := SaveSnooze(alerter.SnoozePath, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	_ = alerter.Handle(low)
+	if len(fake.sent) != 0 {
+		t.Fatalf("暂停期间不应该通知")
+	}
+
+	// 删除暂停时间后立即恢复
+	if err := SaveSnooze(alerter.SnoozePath, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	_ = alerter.Handle(low)
+	if len(fake.sent) != 1 {
+		t.Errorf("恢复后应该补发通知, 实际 %d 条", len(fake.sent))
+	}
+}
+
+func TestParseThresholds(t *testing.T) {
+	if got, err := ParseThresholds("20, 10,5"); err != nil || len(got) != 3 {
+		t.Errorf("解析失败: %v %v", got, err)
+	}
+	for _, s := range []string{"", "abc", "0", "150"} {
+		if _, err := ParseThresholds(s); err == nil {
+			t.Errorf("%q 应该解析失败", s)
+		}
+	}
+}
+
+func TestAlerterPersistedState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alert.json")
+	fake := &fakeNotifier{}
+	now := time.Now()
+
+	// 模拟每次状态栏重绘都是新进程
+	check := func(percent int) {
+		alerter := NewAlerter(fake, []int{30})
+		alerter.Snooze = 0
+		if err := alerter.LoadState(path); err != nil {
+			t.Fatalf("加载状态失败: %v", err)
+		}
+		_ = alerter.Check(&battery.BatteryInfo{Percentage: percent, Available: true}, now)
+		if err := alerter.SaveState(path); err != nil {
+			t.Fatalf("保存状态失败: %v", err)
+		}
+	}
+
+	// 恰好等于阈值时与状态栏的低电量级别一致，不提醒
+	for _, percent := range []int{31, 30, 29, 29, 28, 36, 30, 29} {
+		check(percent)
+	}
+
+	if len(fake.sent) != 2 {
+		t.Errorf("期望 2 条通知，实际 %d 条: %+v", len(fake.sent), fake.sent)
+	}
+}
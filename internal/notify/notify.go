@@ -0,0 +1,110 @@
+// Package notify 通过桌面通知提醒外设低电量
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// AppName 通知中显示的应用名
+const AppName = "tmux-touchpad-battery"
+
+// Urgency 通知的紧急程度
+type Urgency int
+
+const (
+	UrgencyNormal Urgency = iota
+	UrgencyCritical
+)
+
+// String 返回 freedesktop 规范中的紧急程度名称
+func (u Urgency) String() string {
+	if u == UrgencyCritical {
+		return "critical"
+	}
+	return "normal"
+}
+
+// Notification 表示一条通知
+type Notification struct {
+	Title   string
+	Body    string
+	Urgency Urgency
+}
+
+// Notifier 发送通知
+type Notifier interface {
+	Notify(n Notification) error
+}
+
+// ErrUnsupported 当前平台没有可用的通知方式
+var ErrUnsupported = errors.New("当前平台不支持桌面通知")
+
+// Default 返回当前平台的通知方式
+// macOS 使用 osascript；Linux 优先使用 notify-send，没有时通过 gdbus 调用 org.freedesktop.Notifications
+func Default() (Notifier, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return OSAScript{}, nil
+	case "linux", "freebsd", "openbsd", "netbsd":
+		if _, err := exec.LookPath("notify-send"); err == nil {
+			return NotifySend{}, nil
+		}
+		if _, err := exec.LookPath("gdbus"); err == nil {
+			return DBus{}, nil
+		}
+	}
+	return nil, ErrUnsupported
+}
+
+// NotifySend 通过 notify-send 发送通知
+type NotifySend struct{}
+
+// Notify 实现 Notifier
+func (NotifySend) Notify(n Notification) error {
+	cmd := exec.Command("notify-send", "-a", AppName, "-u", n.Urgency.String(), n.Title, n.Body)
+	return cmd.Run()
+}
+
+// DBus 通过 gdbus 直接调用 org.freedesktop.Notifications.Notify
+type DBus struct{}
+
+// Notify 实现 Notifier
+func (DBus) Notify(n Notification) error {
+	cmd := exec.Command("gdbus", "call", "--session",
+		"--dest", "org.freedesktop.Notifications",
+		"--object-path", "/org/freedesktop/Notifications",
+		"--method", "org.freedesktop.Notifications.Notify",
+		AppName, "0", "", n.Title, n.Body, "[]",
+		fmt.Sprintf("{'urgency': <byte %d>}", dbusUrgency(n.Urgency)),
+		"-1",
+	)
+	return cmd.Run()
+}
+
+// dbusUrgency 返回 freedesktop 规范中的紧急程度值（0 低，1 普通，2 紧急）
+func dbusUrgency(u Urgency) int {
+	if u == UrgencyCritical {
+		return 2
+	}
+	return 1
+}
+
+// OSAScript 通过 osascript 的 display notification 发送通知
+type OSAScript struct{}
+
+// appleScriptEscaper 转义 AppleScript 字符串
+var appleScriptEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// Notify 实现 Notifier，macOS 通知没有紧急程度，紧急通知附带提示音
+func (OSAScript) Notify(n Notification) error {
+	script := fmt.Sprintf(`display notification "%s" with title "%s"`,
+		appleScriptEscaper.Replace(n.Body), appleScriptEscaper.Replace(n.Title))
+	if n.Urgency == UrgencyCritical {
+		script += ` sound name "Basso"`
+	}
+	return exec.Command("osascript", "-e", script).Run()
+}
@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/state"
+)
+
+// SnoozeFile 状态目录下保存暂停时间的文件名，tmux 提醒和 serve --notify 共用
+const SnoozeFile = "snooze.json"
+
+// savedSnooze 持久化的暂停时间
+type savedSnooze struct {
+	Until time.Time `json:"until"`
+}
+
+// SnoozePath 返回状态目录下的暂停时间文件路径
+func SnoozePath() (string, error) {
+	dir, err := state.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, SnoozeFile), nil
+}
+
+// LoadSnooze 读取暂停时间，文件不存在时返回零值
+func LoadSnooze(path string) (time.Time, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var saved savedSnooze
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return time.Time{}, err
+	}
+	return saved.Until, nil
+}
+
+// SaveSnooze 保存暂停时间，until 为零值时删除文件，恢复通知
+func SaveSnooze(path string, until time.Time) error {
+	if until.IsZero() {
+		err := os.Remove(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	data, err := json.Marshal(savedSnooze{Until: until})
+	if err != nil {
+		return err
+	}
+	return state.WriteFile(path, data)
+}
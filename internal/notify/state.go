@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/state"
+)
+
+// savedState 持久化的提醒状态，用于每次重绘都重新启动的 tmux 调用
+type savedState struct {
+	Devices map[string]savedDevice `json:"devices"`
+}
+
+// savedDevice 单个设备的持久化状态
+type savedDevice struct {
+	Fired []int     `json:"fired,omitempty"`
+	Last  time.Time `json:"last,omitempty"`
+}
+
+// LoadState 从文件恢复提醒状态，文件不存在时保持初始状态
+func (a *Alerter) LoadState(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var saved savedState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.devices = make(map[string]*deviceState)
+	for key, d := range saved.Devices {
+		st := &deviceState{fired: make(map[int]bool), last: d.Last}
+		for _, threshold := range d.Fired {
+			st.fired[threshold] = true
+		}
+		a.devices[key] = st
+	}
+	a.dirty = false
+	return nil
+}
+
+// SaveState 将提醒状态写入文件，状态没有变化时不写入
+func (a *Alerter) SaveState(path string) error {
+	a.mu.Lock()
+	if !a.dirty {
+		a.mu.Unlock()
+		return nil
+	}
+
+	saved := savedState{Devices: make(map[string]savedDevice)}
+	for key, st := range a.devices {
+		d := savedDevice{Last: st.last}
+		for threshold := range st.fired {
+			d.Fired = append(d.Fired, threshold)
+		}
+		sort.Ints(d.Fired)
+		saved.Devices[key] = d
+	}
+	a.mu.Unlock()
+
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	if err := state.WriteFile(path, data); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.dirty = false
+	a.mu.Unlock()
+	return nil
+}
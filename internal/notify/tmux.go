@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/tmux"
+)
+
+// tmux 提醒方式（@tpb_alert_mode）
+const (
+	TmuxModeOff     = "off"
+	TmuxModeMessage = "message"
+	TmuxModePopup   = "popup"
+	TmuxModeBell    = "bell"
+)
+
+// Tmux 通过 tmux 向所有已连接的客户端发送提醒
+type Tmux struct {
+	Mode string
+}
+
+// shellQuoter 转义 shell 单引号字符串
+var shellQuoter = strings.NewReplacer("'", `'\''`)
+
+// Notify 实现 Notifier
+// message 在状态栏显示消息；popup 打开弹出窗口，按回车关闭；bell 向客户端终端发送响铃
+func (t Tmux) Notify(n Notification) error {
+	clients, err := tmux.ListClients()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, client := range clients {
+		switch t.Mode {
+		case TmuxModeMessage:
+			err = tmux.DisplayMessage(client.Name, n.Title+": "+n.Body)
+		case TmuxModePopup:
+			command := fmt.Sprintf("printf '%%s\\n\\n%%s' '%s' 'Press Enter to close'; read -r _", shellQuoter.Replace(n.Body))
+			err = tmux.DisplayPopup(client.Name, " "+n.Title+" ", command, 50, 6)
+		case TmuxModeBell:
+			err = ringBell(client.TTY)
+		default:
+			return fmt.Errorf("不支持的提醒方式: %q", t.Mode)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ringBell 向客户端终端写入响铃字符
+func ringBell(tty string) error {
+	f, err := os.OpenFile(tty, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString("\a")
+	return err
+}
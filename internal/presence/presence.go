@@ -0,0 +1,257 @@
+// Package presence 跨采样跟踪外设的连接状态，记录断开的设备最后一次出现的时间和电量
+package presence
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+	"github.com/akayj/tmux-touchpad-battery/internal/snapshot"
+	"github.com/akayj/tmux-touchpad-battery/internal/state"
+)
+
+// StateFile 状态目录下保存设备记录的文件名
+const StateFile = "devices.json"
+
+// DefaultForgetAfter 断开超过该时间的设备不再记录
+const DefaultForgetAfter = 30 * 24 * time.Hour
+
+// seenResolution 已连接设备最后出现时间的精度，不到该时间不更新，
+// 电量没有变化时不必每次采样都重写记录文件
+const seenResolution = time.Minute
+
+// Record 一个设备的最后状态
+type Record struct {
+	Name       string    `json:"name"`
+	Serial     string    `json:"serial,omitempty"`
+	Address    string    `json:"address,omitempty"`
+	Class      string    `json:"class,omitempty"`
+	Percentage int       `json:"percentage"`
+	Charging   bool      `json:"charging"`
+	LastSeen   time.Time `json:"last_seen"`
+	Connected  bool      `json:"connected"`
+}
+
+// matches 判断记录与设备是否为同一设备，按序列号、地址、名称依次比较
+func (r *Record) matches(d *battery.BatteryInfo) bool {
+	switch {
+	case r.Serial != "" && d.Serial != "":
+		return r.Serial == d.Serial
+	case r.Address != "" && d.Address != "":
+		return r.Address == d.Address
+	}
+	return r.Name == d.Name
+}
+
+// class 返回记录的设备类别，旧版本的记录没有类别时按名称判断
+func (r *Record) class() string {
+	if r.Class != "" {
+		return r.Class
+	}
+	return (&battery.BatteryInfo{Name: r.Name}).Class()
+}
+
+// Tracker 跟踪设备的连接状态
+// 设置 Path 时记录保存在文件中，常驻模式和单次运行的 tmux 模式共享同一文件
+type Tracker struct {
+	Path        string
+	ForgetAfter time.Duration
+
+	mu      sync.Mutex
+	records []*Record
+	// saved 最近一次读取或写入文件的内容，内容相同时不再写入
+	saved []byte
+}
+
+// NewTracker 创建新的设备跟踪器
+func NewTracker(path string) *Tracker {
+	return &Tracker{Path: path, ForgetAfter: DefaultForgetAfter}
+}
+
+// Open 使用状态目录下的记录文件创建设备跟踪器
+func Open() (*Tracker, error) {
+	dir, err := state.Dir()
+	if err != nil {
+		return nil, err
+	}
+	return NewTracker(filepath.Join(dir, StateFile)), nil
+}
+
+// Handle 实现 daemon.Sink，设备列表获取失败时不更新
+func (t *Tracker) Handle(s *snapshot.Snapshot) error {
+	if s.DevicesErr != nil {
+		return nil
+	}
+	return t.Update(s.Devices, s.Time)
+}
+
+// Update 记录一次采样中出现的设备，之前记录过但本次没有出现的设备标记为断开
+func (t *Tracker) Update(devices []*battery.BatteryInfo, now time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.load(); err != nil {
+		return err
+	}
+
+	seen := make(map[*Record]bool)
+	for _, d := range devices {
+		if !d.Available {
+			continue
+		}
+
+		var rec *Record
+		for _, r := range t.records {
+			if !seen[r] && r.matches(d) {
+				rec = r
+				break
+			}
+		}
+		if rec == nil {
+			rec = &Record{}
+			t.records = append(t.records, rec)
+		}
+		seen[rec] = true
+
+		lastSeen := now
+		if rec.Connected && now.Sub(rec.LastSeen) < seenResolution {
+			lastSeen = rec.LastSeen
+		}
+		*rec = Record{
+			Name:       d.Name,
+			Serial:     firstNonEmpty(d.Serial, rec.Serial),
+			Address:    firstNonEmpty(d.Address, rec.Address),
+			Class:      d.Class(),
+			Percentage: d.Percentage,
+			Charging:   d.IsCharging,
+			LastSeen:   lastSeen,
+			Connected:  true,
+		}
+	}
+
+	kept := t.records[:0]
+	for _, r := range t.records {
+		if !seen[r] {
+			r.Connected = false
+			if t.ForgetAfter > 0 && now.Sub(r.LastSeen) > t.ForgetAfter {
+				continue
+			}
+		}
+		kept = append(kept, r)
+	}
+	t.records = kept
+
+	return t.save()
+}
+
+// Records 返回所有设备记录，已连接的在前，其余按最后出现时间从新到旧排列
+func (t *Tracker) Records() ([]Record, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+
+	records := make([]Record, 0, len(t.records))
+	for _, r := range t.records {
+		records = append(records, *r)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Connected != records[j].Connected {
+			return records[i].Connected
+		}
+		return records[i].LastSeen.After(records[j].LastSeen)
+	})
+	return records, nil
+}
+
+// LastDisconnected 返回最近断开的指定类别的设备，class 为空时不限类别，没有时返回 nil
+func (t *Tracker) LastDisconnected(class string) (*Record, error) {
+	records, err := t.Records()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		if !r.Connected && (class == "" || r.class() == class) {
+			return &r, nil
+		}
+	}
+	return nil, nil
+}
+
+// FormatAgo 返回距今时间的简短文本，如 "just now"、"5m ago"、"2h ago"、"3d ago"
+func FormatAgo(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+}
+
+// load 从文件读取设备记录，没有设置 Path 时使用内存中的记录
+func (t *Tracker) load() error {
+	if t.Path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(t.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.records = nil
+		t.saved = nil
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var records []*Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return err
+	}
+	t.records = records
+	t.saved = data
+	return nil
+}
+
+// save 保存连接记录，记录与文件中的内容相同时不写入
+func (t *Tracker) save() error {
+	if t.Path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(t.records)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(data, t.saved) {
+		return nil
+	}
+	if err := state.WriteFile(t.Path, data); err != nil {
+		return err
+	}
+	t.saved = data
+	return nil
+}
+
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,141 @@
+package presence
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+)
+
+func TestTrackerDisconnect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), StateFile)
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	trackpad := &battery.BatteryInfo{Name: "Magic Trackpad", Serial: "TP1", Percentage: 40, Available: true}
+	keyboard := &battery.BatteryInfo{Name: "Magic Keyboard", Address: "aa-bb", Percentage: 80, Available: true}
+
+	if err := NewTracker(path).Update([]*battery.BatteryInfo{trackpad, keyboard}, start); err != nil {
+		t.Fatal(err)
+	}
+
+	// 新的 Tracker 从文件读取记录，模拟单次运行的 tmux 模式
+	tracker := NewTracker(path)
+	if r, err := tracker.LastDisconnected(battery.ClassTrackpad); err != nil || r != nil {
+		t.Fatalf("LastDisconnected() = %v, %v, want nil", r, err)
+	}
+
+	// 触摸板断开，键盘电量变化
+	keyboard.Percentage = 79
+	if err := tracker.Update([]*battery.BatteryInfo{keyboard}, start.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewTracker(path).LastDisconnected(battery.ClassTrackpad)
+	if err != nil || r == nil {
+		t.Fatalf("LastDisconnected() = %v, %v", r, err)
+	}
+	if r.Serial != "TP1" || r.Percentage != 40 || !r.LastSeen.Equal(start) || r.Connected {
+		t.Errorf("LastDisconnected() = %+v", r)
+	}
+
+	records, _ := tracker.Records()
+	if len(records) != 2 || !records[0].Connected || records[0].Percentage != 79 {
+		t.Errorf("Records() = %+v", records)
+	}
+
+	// 重新连接后按序列号匹配同一条记录
+	trackpad.Percentage = 35
+	if err := tracker.Update([]*battery.BatteryInfo{trackpad, keyboard}, start.Add(3*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	records, _ = tracker.Records()
+	if len(records) != 2 || !records[0].Connected || !records[1].Connected {
+		t.Errorf("重新连接后 Records() = %+v", records)
+	}
+}
+
+func TestTrackerLastDisconnectedClass(t *testing.T) {
+	tracker := NewTracker("")
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	trackpad := &battery.BatteryInfo{Name: "Magic Trackpad", Percentage: 40, Available: true}
+	mouse := &battery.BatteryInfo{Name: "MX Master 3", Kind: battery.ClassMouse, Percentage: 60, Available: true}
+
+	_ = tracker.Update([]*battery.BatteryInfo{trackpad, mouse}, start)
+	_ = tracker.Update([]*battery.BatteryInfo{mouse}, start.Add(time.Hour))
+	// 鼠标断开得更晚，但触摸板的记录不受影响
+	_ = tracker.Update(nil, start.Add(2*time.Hour))
+
+	r, err := tracker.LastDisconnected(battery.ClassTrackpad)
+	if err != nil || r == nil || r.Name != "Magic Trackpad" {
+		t.Fatalf("LastDisconnected(trackpad) = %+v, %v", r, err)
+	}
+	if r, _ := tracker.LastDisconnected(""); r == nil || r.Name != "MX Master 3" {
+		t.Errorf("LastDisconnected(\"\") = %+v", r)
+	}
+}
+
+func TestTrackerSkipsUnchangedWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), StateFile)
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	d := &battery.BatteryInfo{Name: "Magic Trackpad", Percentage: 40, Available: true}
+
+	if err := NewTracker(path).Update([]*battery.BatteryInfo{d}, start); err != nil {
+		t.Fatal(err)
+	}
+	old := start.Add(-time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	// 不到 seenResolution 且电量没有变化时不重写文件
+	if err := NewTracker(path).Update([]*battery.BatteryInfo{d}, start.Add(30*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(old) {
+		t.Errorf("内容没有变化时不应写入文件, ModTime = %v", info.ModTime())
+	}
+
+	d.Percentage = 39
+	if err := NewTracker(path).Update([]*battery.BatteryInfo{d}, start.Add(40*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(path); info.ModTime().Equal(old) {
+		t.Error("电量变化后应写入文件")
+	}
+}
+
+func TestTrackerForget(t *testing.T) {
+	tracker := NewTracker("")
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	d := &battery.BatteryInfo{Name: "Magic Mouse", Percentage: 10, Available: true}
+
+	_ = tracker.Update([]*battery.BatteryInfo{d}, start)
+	_ = tracker.Update(nil, start.Add(DefaultForgetAfter+time.Hour))
+
+	if records, _ := tracker.Records(); len(records) != 0 {
+		t.Errorf("超过 ForgetAfter 的设备应被删除, Records() = %+v", records)
+	}
+}
+
+func TestFormatAgo(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{30 * time.Second, "just now"},
+		{5 * time.Minute, "5m ago"},
+		{2*time.Hour + 30*time.Minute, "2h ago"},
+		{50 * time.Hour, "2d ago"},
+	}
+	for _, tt := range tests {
+		if got := FormatAgo(tt.d); got != tt.want {
+			t.Errorf("FormatAgo(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Package report 将采样结果转换为带版本号的机器可读格式（JSON/YAML）
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/snapshot"
+)
+
+// SchemaVersion 当前输出格式的版本号，字段发生不兼容变化时递增
+const SchemaVersion = 1
+
+// 不可用原因
+const (
+	ReasonNotFound    = "not_found"
+	ReasonError       = "error"
+	ReasonUnsupported = "unsupported"
+)
+
+// Report 表示一次完整的状态报告
+type Report struct {
+	SchemaVersion int       `json:"schema_version"`
+	Timestamp     time.Time `json:"timestamp"`
+	Devices       []Device  `json:"devices"`
+	DevicesError  string    `json:"devices_error,omitempty"`
+	System        System    `json:"system"`
+}
+
+// Device 表示一个外设的电池状态
+type Device struct {
+	Name       string `json:"name"`
+	Serial     string `json:"serial,omitempty"`
+	Address    string `json:"address,omitempty"`
+	Available  bool   `json:"available"`
+	Percentage *int   `json:"percentage"`
+	Charging   bool   `json:"charging"`
+	State      string `json:"state"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// System 表示系统资源使用情况
+type System struct {
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
+	Error     string `json:"error,omitempty"`
+	CPU       Metric `json:"cpu"`
+	GPU       Metric `json:"gpu"`
+}
+
+// Metric 表示一项使用率指标
+type Metric struct {
+	Available    bool     `json:"available"`
+	UsagePercent *float64 `json:"usage_percent"`
+	Reason       string   `json:"reason,omitempty"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// New 根据采样结果生成报告
+func New(s *snapshot.Snapshot) *Report {
+	r := &Report{
+		SchemaVersion: SchemaVersion,
+		Timestamp:     s.Time,
+		Devices:       []Device{},
+	}
+
+	if s.DevicesErr != nil {
+		r.DevicesError = s.DevicesErr.Error()
+	}
+
+	for _, info := range s.Devices {
+		d := Device{
+			Name:      info.Name,
+			Serial:    info.Serial,
+			Address:   info.Address,
+			Available: info.Available,
+			Charging:  info.IsCharging,
+			State:     info.ChargeState().String(),
+		}
+		if info.Available {
+			percentage := info.Percentage
+			d.Percentage = &percentage
+		} else {
+			d.Reason = ReasonNotFound
+		}
+		r.Devices = append(r.Devices, d)
+	}
+
+	sys := s.System
+	switch {
+	case s.SystemErr != nil:
+		r.System.Reason = ReasonError
+		r.System.Error = s.SystemErr.Error()
+	case !sys.Available:
+		r.System.Reason = ReasonUnsupported
+	default:
+		r.System.Available = true
+
+		cpu := sys.CPUUsage
+		r.System.CPU = Metric{Available: true, UsagePercent: &cpu}
+
+		if sys.GPUErr != nil {
+			r.System.GPU = Metric{Reason: ReasonError, Error: sys.GPUErr.Error()}
+		} else {
+			gpu := sys.GPUUsage
+			r.System.GPU = Metric{Available: true, UsagePercent: &gpu}
+		}
+	}
+
+	return r
+}
+
+// Write 按指定格式输出报告，支持 json 和 yaml
+func Write(w io.Writer, r *Report, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "yaml":
+		return encodeYAML(w, r)
+	}
+	return fmt.Errorf("不支持的输出格式: %q", format)
+}
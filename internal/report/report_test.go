@@ -0,0 +1,60 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/snapshot/snapshottest"
+)
+
+func TestWriteYAML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, New(snapshottest.New()), "yaml"); err != nil {
+		t.Fatalf("输出 YAML 失败: %v", err)
+	}
+
+	want := `schema_version: 1
+timestamp: "2025-01-02T03:04:05Z"
+devices:
+  - name: "Magic Trackpad"
+    serial: "CC01"
+    available: true
+    percentage: 42
+    charging: true
+    state: "charging"
+system:
+  available: true
+  cpu:
+    available: true
+    usage_percent: 12.5
+  gpu:
+    available: false
+    usage_percent: null
+    reason: "error"
+    error: "permission denied"
+`
+	if buf.String() != want {
+		t.Errorf("YAML 输出 =\n%s\n期望\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	s := snapshottest.New()
+	s.SystemErr = errors.New("top failed")
+
+	var buf bytes.Buffer
+	if err := Write(&buf, New(s), "json"); err != nil {
+		t.Fatalf("输出 JSON 失败: %v", err)
+	}
+
+	for _, want := range []string{`"schema_version": 1`, `"percentage": 42`, `"reason": "error"`, `"error": "top failed"`} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("JSON 输出缺少 %s:\n%s", want, buf.String())
+		}
+	}
+
+	if err := Write(&buf, New(s), "xml"); err == nil {
+		t.Error("不支持的格式应该返回错误")
+	}
+}
@@ -0,0 +1,155 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// encodeYAML 将报告按 json 标签输出为 YAML
+// 报告结构固定且简单，只需要支持结构体、切片、指针和基本类型
+func encodeYAML(w io.Writer, v any) error {
+	var b strings.Builder
+	if err := writeYAMLFields(&b, reflect.Indirect(reflect.ValueOf(v)), 0); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeYAMLFields 输出结构体的所有字段
+func writeYAMLFields(b *strings.Builder, v reflect.Value, indent int) error {
+	t := v.Type()
+	pad := strings.Repeat(" ", indent)
+
+	for i := 0; i < t.NumField(); i++ {
+		name, omitEmpty := jsonName(t.Field(i))
+		if name == "" {
+			continue
+		}
+
+		fv := v.Field(i)
+		if omitEmpty && fv.IsZero() {
+			continue
+		}
+
+		switch {
+		case isYAMLScalar(fv):
+			scalar, err := yamlScalar(fv)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(b, "%s%s: %s\n", pad, name, scalar)
+
+		case fv.Kind() == reflect.Struct:
+			fmt.Fprintf(b, "%s%s:\n", pad, name)
+			if err := writeYAMLFields(b, fv, indent+2); err != nil {
+				return err
+			}
+
+		case fv.Kind() == reflect.Slice:
+			if fv.Len() == 0 {
+				fmt.Fprintf(b, "%s%s: []\n", pad, name)
+				continue
+			}
+			fmt.Fprintf(b, "%s%s:\n", pad, name)
+			for j := 0; j < fv.Len(); j++ {
+				if err := writeYAMLItem(b, fv.Index(j), indent+2); err != nil {
+					return err
+				}
+			}
+
+		default:
+			return fmt.Errorf("YAML 不支持的类型: %s", fv.Type())
+		}
+	}
+
+	return nil
+}
+
+// writeYAMLItem 输出列表中的一项，结构体的第一个字段与 "- " 位于同一行
+func writeYAMLItem(b *strings.Builder, v reflect.Value, indent int) error {
+	pad := strings.Repeat(" ", indent)
+
+	if isYAMLScalar(v) {
+		scalar, err := yamlScalar(v)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "%s- %s\n", pad, scalar)
+		return nil
+	}
+
+	var item strings.Builder
+	if err := writeYAMLFields(&item, v, indent+2); err != nil {
+		return err
+	}
+
+	text := item.String()
+	if text == "" {
+		fmt.Fprintf(b, "%s- {}\n", pad)
+		return nil
+	}
+	b.WriteString(pad + "- " + text[indent+2:])
+	return nil
+}
+
+// jsonName 返回字段的 json 名称以及是否设置了 omitempty
+func jsonName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" || !f.IsExported() {
+		return "", false
+	}
+
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = f.Name
+	}
+	return name, strings.Contains(opts, "omitempty")
+}
+
+// isYAMLScalar 判断是否按标量输出
+func isYAMLScalar(v reflect.Value) bool {
+	if v.Type() == reflect.TypeOf(time.Time{}) {
+		return true
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		return v.IsNil() || isYAMLScalar(v.Elem())
+	case reflect.Struct, reflect.Slice:
+		return false
+	}
+	return true
+}
+
+// yamlScalar 输出标量值，字符串使用双引号并按 JSON 规则转义（YAML 兼容）
+func yamlScalar(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "null", nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	}
+
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,36 @@
+// Package snapshot 在某一时刻采集所有设备电池和系统信息
+package snapshot
+
+import (
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+	"github.com/akayj/tmux-touchpad-battery/internal/system"
+)
+
+// Snapshot 表示一次采样的结果
+type Snapshot struct {
+	Time time.Time
+
+	// Devices 所有报告电池电量的外设，DevicesErr 为获取失败的原因
+	Devices    []*battery.BatteryInfo
+	DevicesErr error
+
+	// System 系统信息，SystemErr 为获取失败的原因
+	System    *system.SystemInfo
+	SystemErr error
+}
+
+// Collect 采集当前的设备和系统信息，单项失败不会影响其他项
+func Collect() *Snapshot {
+	s := &Snapshot{Time: time.Now()}
+
+	s.Devices, s.DevicesErr = battery.GetDevices()
+
+	s.System, s.SystemErr = system.GetSystemInfo()
+	if s.System == nil {
+		s.System = &system.SystemInfo{}
+	}
+
+	return s
+}
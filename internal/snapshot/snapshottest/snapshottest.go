@@ -0,0 +1,30 @@
+// Package snapshottest 提供测试中共用的采样结果
+package snapshottest
+
+import (
+	"errors"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+	"github.com/akayj/tmux-touchpad-battery/internal/snapshot"
+	"github.com/akayj/tmux-touchpad-battery/internal/system"
+)
+
+// Time 采样时间，即 2025-01-02T03:04:05Z
+var Time = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// New 返回一次采样：正在充电、电量 42% 的触摸板（序列号 CC01），CPU 使用率 12.5%，GPU 不可用
+// 每次调用返回新的副本，测试可以直接修改
+func New() *snapshot.Snapshot {
+	return &snapshot.Snapshot{
+		Time: Time,
+		Devices: []*battery.BatteryInfo{
+			{Name: "Magic Trackpad", Serial: "CC01", Percentage: 42, Available: true, IsCharging: true, State: battery.StateCharging},
+		},
+		System: &system.SystemInfo{
+			CPUUsage:  12.5,
+			Available: true,
+			GPUErr:    errors.New("permission denied"),
+		},
+	}
+}
@@ -0,0 +1,55 @@
+// Package state 定位程序持久化状态（历史记录、告警状态等）的存放目录，并提供原子写入
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// AppName 状态目录下的子目录名
+const AppName = "tmux-touchpad-battery"
+
+// Dir 返回状态目录，优先使用 $XDG_STATE_HOME，否则为 ~/.local/state
+// 目录不存在时自动创建
+func Dir() (string, error) {
+	base := os.Getenv("XDG_STATE_HOME")
+	if base == "" || !filepath.IsAbs(base) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		if home == "" {
+			return "", errors.New("无法确定用户主目录")
+		}
+		base = filepath.Join(home, ".local", "state")
+	}
+
+	dir := filepath.Join(base, AppName)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// WriteFile 先在同一目录写临时文件再重命名，避免并发的 tmux 调用或其他读取者读到不完整的内容
+// 文件权限为 0644
+func WriteFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,278 @@
+// Package style 实现 tmux 样式字符串的解析、校验与转换
+package style
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/color"
+)
+
+// Attr 表示 tmux 文本属性的位集合
+type Attr uint16
+
+const (
+	AttrBold Attr = 1 << iota
+	AttrDim
+	AttrUnderscore
+	AttrBlink
+	AttrReverse
+	AttrHidden
+	AttrItalics
+	AttrStrikethrough
+	AttrOverline
+	AttrDoubleUnderscore
+	AttrCurlyUnderscore
+	AttrDottedUnderscore
+	AttrDashedUnderscore
+)
+
+// attrNames 属性名，顺序即输出顺序
+var attrNames = []struct {
+	name string
+	attr Attr
+}{
+	{"bold", AttrBold},
+	{"dim", AttrDim},
+	{"underscore", AttrUnderscore},
+	{"blink", AttrBlink},
+	{"reverse", AttrReverse},
+	{"hidden", AttrHidden},
+	{"italics", AttrItalics},
+	{"strikethrough", AttrStrikethrough},
+	{"overline", AttrOverline},
+	{"double-underscore", AttrDoubleUnderscore},
+	{"curly-underscore", AttrCurlyUnderscore},
+	{"dotted-underscore", AttrDottedUnderscore},
+	{"dashed-underscore", AttrDashedUnderscore},
+}
+
+// attrAliases tmux 接受的属性别名
+var attrAliases = map[string]Attr{
+	"bright": AttrBold,
+}
+
+// Style 表示解析后的 tmux 样式
+type Style struct {
+	Fg *color.Color
+	Bg *color.Color
+	Us *color.Color
+
+	// Attrs 需要开启的属性，NoAttrs 需要显式关闭的属性（如 nobold）
+	Attrs   Attr
+	NoAttrs Attr
+
+	// Reset 对应 tmux 的 default，恢复默认的颜色和属性，之前设置的颜色和属性不再生效
+	Reset bool
+	// ClearAttrs 对应 tmux 的 none，清除此前的所有属性
+	ClearAttrs bool
+}
+
+// Parse 解析 tmux 样式字符串，例如 "fg=red,bg=black,bold,reverse"
+func Parse(s string) (Style, error) {
+	var st Style
+
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+
+	for _, field := range fields {
+		field = strings.ToLower(strings.TrimSpace(field))
+
+		if key, value, ok := strings.Cut(field, "="); ok {
+			c, err := color.Parse(value)
+			if err != nil {
+				return Style{}, fmt.Errorf("样式 %q 中的颜色无效: %w", s, err)
+			}
+
+			switch key {
+			case "fg":
+				st.Fg = &c
+			case "bg":
+				st.Bg = &c
+			case "us":
+				st.Us = &c
+			default:
+				return Style{}, fmt.Errorf("样式 %q 中不支持的选项: %q", s, key)
+			}
+			continue
+		}
+
+		// 与 tmux 一致，default 同时清除此前设置的颜色和属性
+		if field == "default" {
+			st = Style{Reset: true}
+			continue
+		}
+		if field == "none" {
+			st.ClearAttrs = true
+			st.Attrs, st.NoAttrs = 0, 0
+			continue
+		}
+
+		negate := false
+		name := field
+		if strings.HasPrefix(name, "no") {
+			if _, err := lookupAttr(name); err != nil {
+				negate = true
+				name = strings.TrimPrefix(name, "no")
+			}
+		}
+
+		attr, err := lookupAttr(name)
+		if err != nil {
+			return Style{}, fmt.Errorf("样式 %q 中不支持的属性: %q", s, field)
+		}
+
+		if negate {
+			st.NoAttrs |= attr
+			st.Attrs &^= attr
+		} else {
+			st.Attrs |= attr
+			st.NoAttrs &^= attr
+		}
+	}
+
+	return st, nil
+}
+
+// lookupAttr 根据名称查找属性
+func lookupAttr(name string) (Attr, error) {
+	if attr, ok := attrAliases[name]; ok {
+		return attr, nil
+	}
+	for _, a := range attrNames {
+		if a.name == name {
+			return a.attr, nil
+		}
+	}
+	return 0, fmt.Errorf("未知属性: %q", name)
+}
+
+// IsZero 判断样式是否为空
+func (st Style) IsZero() bool {
+	return st.Fg == nil && st.Bg == nil && st.Us == nil &&
+		st.Attrs == 0 && st.NoAttrs == 0 && !st.Reset && !st.ClearAttrs
+}
+
+// WithFg 返回设置了前景色的样式副本
+func (st Style) WithFg(c color.Color) Style {
+	st.Fg = &c
+	return st
+}
+
+// WithBg 返回设置了背景色的样式副本
+func (st Style) WithBg(c color.Color) Style {
+	st.Bg = &c
+	return st
+}
+
+// WithAttrs 返回添加了属性的样式副本
+func (st Style) WithAttrs(attrs Attr) Style {
+	st.Attrs |= attrs
+	st.NoAttrs &^= attrs
+	return st
+}
+
+// String 返回 tmux 样式字符串（不含 #[ ]）
+func (st Style) String() string {
+	var parts []string
+
+	if st.Reset {
+		parts = append(parts, "default")
+	}
+	if st.Fg != nil {
+		parts = append(parts, "fg="+st.Fg.String())
+	}
+	if st.Bg != nil {
+		parts = append(parts, "bg="+st.Bg.String())
+	}
+	if st.Us != nil {
+		parts = append(parts, "us="+st.Us.String())
+	}
+	if st.ClearAttrs {
+		parts = append(parts, "none")
+	}
+
+	for _, a := range attrNames {
+		if st.Attrs&a.attr != 0 {
+			parts = append(parts, a.name)
+		} else if st.NoAttrs&a.attr != 0 {
+			parts = append(parts, "no"+a.name)
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// Tmux 返回 tmux 格式标记，空样式返回空字符串
+func (st Style) Tmux() string {
+	if st.IsZero() {
+		return ""
+	}
+	return "#[" + st.String() + "]"
+}
+
+// sgrAttrs 属性对应的 ANSI SGR 参数
+var sgrAttrs = []struct {
+	attr Attr
+	code string
+}{
+	{AttrBold, "1"},
+	{AttrDim, "2"},
+	{AttrItalics, "3"},
+	{AttrUnderscore | AttrDoubleUnderscore | AttrCurlyUnderscore | AttrDottedUnderscore | AttrDashedUnderscore, "4"},
+	{AttrBlink, "5"},
+	{AttrReverse, "7"},
+	{AttrHidden, "8"},
+	{AttrStrikethrough, "9"},
+	{AttrOverline, "53"},
+}
+
+// SGR 返回 ANSI SGR 序列（如 "\x1b[1;31m"），空样式返回空字符串
+func (st Style) SGR() string {
+	var codes []string
+
+	for _, a := range sgrAttrs {
+		if st.Attrs&a.attr != 0 {
+			codes = append(codes, a.code)
+		}
+	}
+	if st.Fg != nil {
+		codes = append(codes, st.Fg.SGR(false))
+	}
+	if st.Bg != nil {
+		codes = append(codes, st.Bg.SGR(true))
+	}
+
+	if len(codes) == 0 {
+		return ""
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m"
+}
+
+// Lipgloss 转换为 lipgloss 样式，用于终端显示
+func (st Style) Lipgloss() lipgloss.Style {
+	ls := lipgloss.NewStyle()
+
+	if st.Fg != nil {
+		ls = ls.Foreground(st.Fg.Lipgloss())
+	}
+	if st.Bg != nil {
+		ls = ls.Background(st.Bg.Lipgloss())
+	}
+
+	underline := AttrUnderscore | AttrDoubleUnderscore | AttrCurlyUnderscore |
+		AttrDottedUnderscore | AttrDashedUnderscore
+
+	// lipgloss 没有 hidden 和 overline，忽略这两个属性
+	return ls.
+		Bold(st.Attrs&AttrBold != 0).
+		Faint(st.Attrs&AttrDim != 0).
+		Underline(st.Attrs&underline != 0).
+		Blink(st.Attrs&AttrBlink != 0).
+		Reverse(st.Attrs&AttrReverse != 0).
+		Italic(st.Attrs&AttrItalics != 0).
+		Strikethrough(st.Attrs&AttrStrikethrough != 0)
+}
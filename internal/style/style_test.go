@@ -0,0 +1,58 @@
+package style
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"fg=red", "fg=red"},
+		{"fg=red,bg=black,bold,reverse", "fg=red,bg=black,bold,reverse"},
+		{"bg=colour235 fg=#ff8800", "fg=#ff8800,bg=colour235"},
+		{"bright,blink", "bold,blink"},
+		{"bold,nobold", "nobold"},
+		{"noitalics,us=brightred,curly-underscore", "us=brightred,noitalics,curly-underscore"},
+		{"default,fg=green", "default,fg=green"},
+		{"fg=red,default", "default"},
+		{"fg=red,bg=blue,bold,none,default,italics", "default,italics"},
+		{"bold,none,fg=blue,italics", "fg=blue,none,italics"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		st, err := Parse(tt.input)
+		if err != nil {
+			t.Errorf("解析样式 %q 失败: %v", tt.input, err)
+			continue
+		}
+		if got := st.String(); got != tt.want {
+			t.Errorf("Parse(%q).String() = %q，期望 %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{"fg=purple", "align=left", "sparkle", "bg=colour999", "nosparkle"} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("无效样式 %q 应该返回错误", input)
+		}
+	}
+}
+
+func TestTmux(t *testing.T) {
+	st, err := Parse("fg=red")
+	if err != nil {
+		t.Fatalf("解析样式失败: %v", err)
+	}
+
+	if got := st.WithAttrs(AttrBlink).Tmux(); got != "#[fg=red,blink]" {
+		t.Errorf("Tmux() = %q，期望 %q", got, "#[fg=red,blink]")
+	}
+
+	if got := (Style{}).Tmux(); got != "" {
+		t.Errorf("空样式的 Tmux() 应该为空，实际: %q", got)
+	}
+}
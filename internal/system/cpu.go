@@ -56,6 +56,7 @@ func GetSystemInfo() (*SystemInfo, error) {
 		// 如果获取 GPU 使用率失败，只记录错误但不中断
 		// GPU 使用率将保持为 0
 		info.GPUUsage = 0
+		info.GPUErr = err
 	} else {
 		info.GPUUsage = gpuUsage
 	}
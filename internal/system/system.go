@@ -5,4 +5,7 @@ type SystemInfo struct {
 	CPUUsage  float64
 	GPUUsage  float64
 	Available bool
+
+	// GPUErr 记录获取 GPU 使用率失败的原因，此时 GPUUsage 为 0
+	GPUErr error
 }
@@ -0,0 +1,53 @@
+package tmux
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Client 表示一个连接到 tmux 服务器的客户端
+type Client struct {
+	Name string
+	TTY  string
+}
+
+// ListClients 列出所有已连接的客户端
+func ListClients() ([]Client, error) {
+	cmd := exec.Command("tmux", "list-clients", "-F", "#{client_name}\t#{client_tty}")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var clients []Client
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		name, tty, ok := strings.Cut(line, "\t")
+		if !ok || name == "" {
+			continue
+		}
+		clients = append(clients, Client{Name: name, TTY: tty})
+	}
+	return clients, nil
+}
+
+// DisplayMessage 在指定客户端的状态栏显示消息
+// 消息中的 # 会被转义，避免被当作 tmux 格式解析
+func DisplayMessage(client, message string) error {
+	cmd := exec.Command("tmux", "display-message", "-c", client, strings.ReplaceAll(message, "#", "##"))
+	return cmd.Run()
+}
+
+// DisplayPopup 在指定客户端打开弹出窗口运行 shell 命令，命令退出后关闭
+// display-popup 会一直运行到弹出窗口关闭，这里只启动不等待，避免阻塞状态栏重绘
+func DisplayPopup(client, title, command string, width, height int) error {
+	cmd := exec.Command("tmux", "display-popup", "-c", client, "-E",
+		"-T", strings.ReplaceAll(title, "#", "##"),
+		"-w", strconv.Itoa(width), "-h", strconv.Itoa(height),
+		command,
+	)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
@@ -10,7 +10,9 @@ import (
 type Config struct {
 	PercentPrefix     string
 	PercentSuffix     string
+	Format            string
 	ColorCharging     string
+	ColorFull         string
 	ColorHigh         string
 	ColorMedium       string
 	ColorStress       string
@@ -18,59 +20,260 @@ type Config struct {
 	MediumThreshold   int
 	NotShowThreshold  int
 	BlinkOnLowBattery bool
+	LevelHysteresis   int
+	LevelMinDuration  int
+	AlertMode         string
 	ChargingIcon      string
 	ShowChargingIcon  bool
+	FullIcon          string
+	ShowFull          bool
+
+	// 颜色模式相关配置
+	ColorMode     string
+	GradientStops string
+	TrueColor     bool
+
+	// 每个级别的完整 tmux 样式，设置后优先于颜色配置
+	StyleCharging string
+	StyleFull     string
+	StyleHigh     string
+	StyleMedium   string
+	StyleStress   string
+
+	// powerline 相关配置
+	Powerline               bool
+	PowerlineSide           string
+	PowerlineLeftSeparator  string
+	PowerlineRightSeparator string
+	PowerlineStatusBg       string
+	PowerlineFg             string
+	PowerlineCPUBg          string
+	PowerlineGPUBg          string
 
 	// 系统监控相关配置
 	ShowCPUInfo      bool
 	ShowGPUInfo      bool
 	SystemInfoPrefix string
 	SystemInfoSuffix string
+
+	// 主题相关配置，未设置的颜色由主题填充
+	Theme        string
+	ColorCPU     string
+	ColorGPU     string
+	ColorTitleFg string
+	ColorTitleBg string
+	ColorDetail  string
+	ColorMuted   string
+	ColorError   string
+	ColorWarning string
+
+	// 历史记录相关配置
+	History         bool
+	HistoryInterval int
+
+	// 断开设备相关配置
+	ShowDisconnected   bool
+	DisconnectedFormat string
+
+	// 迷你图相关配置，数据取自历史记录，每一格的间隔为 HistoryInterval
+	Sparkline       string
+	SparklineLength int
+
+	// 事件相关配置
+	CriticalThreshold int
+	FullThreshold     int
+	CPUHighThreshold  int
+	// Hooks 事件类型（如 battery.low）到钩子命令的映射，"*" 对所有事件运行
+	Hooks map[string]string
+
+	// webhook 相关配置
+	WebhookURL    string
+	WebhookFormat string
+	WebhookEvents string
+
+	// DeviceOverrides 按设备类别或名称覆盖的显示配置，见 ForDevice
+	DeviceOverrides []DeviceOverride
+
+	// 设备筛选和排序，元素为设备类别或名称 glob，见 DeviceOrder
+	Devices        []string
+	ExcludeDevices []string
 }
 
+// HookEvents 可以配置钩子的事件类型，选项名为 @tpb_hook_ 加上将 "." 替换为 "_" 的事件类型
+var HookEvents = []string{
+	"battery.low",
+	"battery.critical",
+	"battery.charging_started",
+	"battery.full",
+	"device.connected",
+	"device.disconnected",
+	"cpu.high",
+}
+
+// DefaultPercentPrefix @tpb_percent_prefix 的默认值
+const DefaultPercentPrefix = "Touchpad:"
+
 // GetConfig 获取 tmux 配置
+// 所有 @tpb_ 选项取自同一次 show-options 的输出；颜色选项未设置时保持为空，由 display.ApplyTheme 按主题填充
 func GetConfig() *Config {
+	return newConfig(loadOptions())
+}
+
+// newConfig 根据读取的选项创建配置，未设置的选项使用默认值
+func newConfig(opts *options) *Config {
 	return &Config{
-		PercentPrefix:     getTmuxOption("@tpb_percent_prefix", "Touchpad:"),
-		PercentSuffix:     getTmuxOption("@tpb_percent_suffix", "%"),
-		ColorCharging:     getTmuxOption("@tpb_color_charging", "green"),
-		ColorHigh:         getTmuxOption("@tpb_color_high", "white"),
-		ColorMedium:       getTmuxOption("@tpb_color_medium", "yellow"),
-		ColorStress:       getTmuxOption("@tpb_color_stress", "red"),
-		StressThreshold:   getTmuxOptionInt("@tpb_stress_threshold", 30),
-		MediumThreshold:   getTmuxOptionInt("@tpb_medium_threshold", 80),
-		NotShowThreshold:  getTmuxOptionInt("@tpb_not_show_threshold", 100),
-		BlinkOnLowBattery: getTmuxOptionBool("@tpb_blink_on_low_battery", false),
-		ChargingIcon:      getTmuxOption("@tpb_charging_icon", "⚡"),
-		ShowChargingIcon:  getTmuxOptionBool("@tpb_show_charging_icon", true),
+		PercentPrefix:     opts.get("@tpb_percent_prefix", DefaultPercentPrefix),
+		PercentSuffix:     opts.get("@tpb_percent_suffix", "%"),
+		Format:            opts.get("@tpb_format", "{prefix}{percent}{suffix}{icon}"),
+		ColorCharging:     opts.get("@tpb_color_charging", ""),
+		ColorFull:         opts.get("@tpb_color_full", ""),
+		ColorHigh:         opts.get("@tpb_color_high", ""),
+		ColorMedium:       opts.get("@tpb_color_medium", ""),
+		ColorStress:       opts.get("@tpb_color_stress", ""),
+		StressThreshold:   opts.getInt("@tpb_stress_threshold", 30),
+		MediumThreshold:   opts.getInt("@tpb_medium_threshold", 80),
+		NotShowThreshold:  opts.getInt("@tpb_not_show_threshold", 100),
+		BlinkOnLowBattery: opts.getBool("@tpb_blink_on_low_battery", false),
+		LevelHysteresis:   opts.getInt("@tpb_level_hysteresis", 0),
+		LevelMinDuration:  opts.getInt("@tpb_level_min_duration", 0),
+		AlertMode:         opts.get("@tpb_alert_mode", "off"),
+		ChargingIcon:      opts.get("@tpb_charging_icon", "⚡"),
+		ShowChargingIcon:  opts.getBool("@tpb_show_charging_icon", true),
+		FullIcon:          opts.get("@tpb_full_icon", "🔌"),
+		ShowFull:          opts.getBool("@tpb_show_full", true),
+
+		// 颜色模式相关配置
+		ColorMode:     opts.get("@tpb_color_mode", "threshold"),
+		GradientStops: opts.get("@tpb_gradient_stops", "red@10,yellow@50,green@90"),
+		TrueColor:     opts.getTrueColor("@tpb_true_color"),
+
+		// 每个级别的完整样式
+		StyleCharging: opts.get("@tpb_style_charging", ""),
+		StyleFull:     opts.get("@tpb_style_full", ""),
+		StyleHigh:     opts.get("@tpb_style_high", ""),
+		StyleMedium:   opts.get("@tpb_style_medium", ""),
+		StyleStress:   opts.get("@tpb_style_stress", ""),
+
+		// powerline 相关配置
+		Powerline:               opts.getBool("@tpb_powerline", false),
+		PowerlineSide:           opts.get("@tpb_powerline_side", "right"),
+		PowerlineLeftSeparator:  opts.get("@tpb_powerline_left_separator", "\ue0b2"),
+		PowerlineRightSeparator: opts.get("@tpb_powerline_right_separator", "\ue0b0"),
+		PowerlineStatusBg:       opts.get("@tpb_powerline_status_bg", "default"),
+		PowerlineFg:             opts.get("@tpb_powerline_fg", ""),
+		PowerlineCPUBg:          opts.get("@tpb_powerline_cpu_bg", ""),
+		PowerlineGPUBg:          opts.get("@tpb_powerline_gpu_bg", ""),
 
 		// 系统监控相关配置
-		ShowCPUInfo:      getTmuxOptionBool("@tpb_show_cpu_info", true),
-		ShowGPUInfo:      getTmuxOptionBool("@tpb_show_gpu_info", true),
-		SystemInfoPrefix: getTmuxOption("@tpb_system_info_prefix", ""),
-		SystemInfoSuffix: getTmuxOption("@tpb_system_info_suffix", ""),
+		ShowCPUInfo:      opts.getBool("@tpb_show_cpu_info", true),
+		ShowGPUInfo:      opts.getBool("@tpb_show_gpu_info", true),
+		SystemInfoPrefix: opts.get("@tpb_system_info_prefix", ""),
+		SystemInfoSuffix: opts.get("@tpb_system_info_suffix", ""),
+
+		// 主题相关配置
+		Theme:        opts.get("@tpb_theme", "default"),
+		ColorCPU:     opts.get("@tpb_color_cpu", ""),
+		ColorGPU:     opts.get("@tpb_color_gpu", ""),
+		ColorTitleFg: opts.get("@tpb_color_title_fg", ""),
+		ColorTitleBg: opts.get("@tpb_color_title_bg", ""),
+		ColorDetail:  opts.get("@tpb_color_detail", ""),
+		ColorMuted:   opts.get("@tpb_color_muted", ""),
+		ColorError:   opts.get("@tpb_color_error", ""),
+		ColorWarning: opts.get("@tpb_color_warning", ""),
+
+		// 历史记录相关配置
+		History:         opts.getBool("@tpb_history", true),
+		HistoryInterval: opts.getInt("@tpb_history_interval", 60),
+
+		// 断开设备相关配置
+		ShowDisconnected:   opts.getBool("@tpb_show_disconnected", false),
+		DisconnectedFormat: opts.get("@tpb_disconnected_format", "{prefix}—({ago})"),
+
+		// 迷你图相关配置
+		Sparkline:       opts.get("@tpb_sparkline", ""),
+		SparklineLength: opts.getInt("@tpb_sparkline_length", 10),
+
+		// 事件相关配置
+		CriticalThreshold: opts.getInt("@tpb_critical_threshold", 10),
+		FullThreshold:     opts.getInt("@tpb_full_threshold", 100),
+		CPUHighThreshold:  opts.getInt("@tpb_cpu_high_threshold", 90),
+		Hooks:             opts.getHooks(),
+
+		// webhook 相关配置
+		WebhookURL:    opts.get("@tpb_webhook_url", ""),
+		WebhookFormat: opts.get("@tpb_webhook_format", "generic"),
+		WebhookEvents: opts.get("@tpb_webhook_events", "battery.low,battery.critical"),
+
+		// 设备覆盖配置
+		DeviceOverrides: opts.getDeviceOverrides(),
+		Devices:         splitList(opts.get("@tpb_devices", "")),
+		ExcludeDevices:  splitList(opts.get("@tpb_exclude", "")),
+	}
+}
+
+// getHooks 获取事件钩子配置，@tpb_hook 对所有事件运行
+func (o *options) getHooks() map[string]string {
+	hooks := make(map[string]string)
+	if command := o.get("@tpb_hook", ""); command != "" {
+		hooks["*"] = command
 	}
+	for _, event := range HookEvents {
+		option := "@tpb_hook_" + strings.ReplaceAll(event, ".", "_")
+		if command := o.get(option, ""); command != "" {
+			hooks[event] = command
+		}
+	}
+	return hooks
+}
+
+// splitList 拆分逗号分隔的列表，忽略空元素
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// optionPrefix 本插件所有选项的前缀
+const optionPrefix = "@tpb_"
+
+// options 一次读取的所有 @tpb_ 选项
+type options struct {
+	// names 按 show-options 输出顺序排列的选项名
+	names  []string
+	values map[string]string
 }
 
-// getTmuxOption 获取 tmux 选项值
-func getTmuxOption(option, defaultValue string) string {
-	cmd := exec.Command("tmux", "show-option", "-gqv", option)
+// loadOptions 通过一次 show-options 读取所有 @tpb_ 选项，tmux 不可用时所有选项都使用默认值
+func loadOptions() *options {
+	cmd := exec.Command("tmux", "show-options", "-g")
 	output, err := cmd.Output()
 	if err != nil {
-		return defaultValue
+		return &options{values: map[string]string{}}
 	}
+	return parseOptions(string(output))
+}
 
-	value := strings.TrimSpace(string(output))
-	if value == "" {
-		return defaultValue
-	}
+// parseOptions 解析 show-options 的输出
+func parseOptions(output string) *options {
+	names, values := parseShowOptions(output, optionPrefix)
+	return &options{names: names, values: values}
+}
 
-	return value
+// get 获取选项值，未设置或为空时返回默认值
+func (o *options) get(option, defaultValue string) string {
+	if value := o.values[option]; value != "" {
+		return value
+	}
+	return defaultValue
 }
 
-// getTmuxOptionInt 获取 tmux 选项整数值
-func getTmuxOptionInt(option string, defaultValue int) int {
-	value := getTmuxOption(option, "")
+// getInt 获取选项整数值
+func (o *options) getInt(option string, defaultValue int) int {
+	value := o.get(option, "")
 	if value == "" {
 		return defaultValue
 	}
@@ -83,9 +286,9 @@ func getTmuxOptionInt(option string, defaultValue int) int {
 	return intValue
 }
 
-// getTmuxOptionBool 获取 tmux 选项布尔值
-func getTmuxOptionBool(option string, defaultValue bool) bool {
-	value := getTmuxOption(option, "")
+// getBool 获取选项布尔值
+func (o *options) getBool(option string, defaultValue bool) bool {
+	value := o.get(option, "")
 	if value == "" {
 		return defaultValue
 	}
@@ -101,6 +304,28 @@ func getTmuxOptionBool(option string, defaultValue bool) bool {
 	}
 }
 
+// getTrueColor 获取真彩色选项，auto 时根据客户端终端特性判断
+func (o *options) getTrueColor(option string) bool {
+	value := o.get(option, "auto")
+	if strings.ToLower(value) != "auto" {
+		return o.getBool(option, false)
+	}
+
+	cmd := exec.Command("tmux", "display-message", "-p", "#{client_termfeatures}")
+	output, err := cmd.Output()
+	if err != nil {
+		return false
+	}
+
+	for _, feature := range strings.Split(strings.TrimSpace(string(output)), ",") {
+		if feature == "RGB" {
+			return true
+		}
+	}
+
+	return false
+}
+
 // SetTmuxOption 设置 tmux 选项
 func SetTmuxOption(option, value string) error {
 	cmd := exec.Command("tmux", "set-option", "-gq", option, value)
@@ -0,0 +1,40 @@
+package tmux
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewConfigFromShowOptions(t *testing.T) {
+	output := `status-left "[#S] "
+@tpb_percent_prefix "TP: "
+@tpb_stress_threshold 20
+@tpb_level_hysteresis 3
+@tpb_blink_on_low_battery on
+@tpb_true_color off
+@tpb_hook_battery_low "say \"low\""
+@tpb_device_mouse_prefix "M:"
+@tpb_devices trackpad,mouse
+@tpb_medium_threshold abc
+`
+	config := newConfig(parseOptions(output))
+
+	// 与 show-option -v 一样去掉值两端的空白
+	if config.PercentPrefix != "TP:" || config.StressThreshold != 20 || config.LevelHysteresis != 3 ||
+		!config.BlinkOnLowBattery || config.TrueColor {
+		t.Errorf("选项值错误: %+v", config)
+	}
+	// 未设置或无效的选项使用默认值
+	if config.PercentSuffix != "%" || config.MediumThreshold != 80 || config.LevelMinDuration != 0 || !config.History {
+		t.Errorf("默认值错误: %+v", config)
+	}
+	if got := config.Hooks["battery.low"]; got != `say "low"` || len(config.Hooks) != 1 {
+		t.Errorf("Hooks = %v", config.Hooks)
+	}
+	if len(config.DeviceOverrides) != 1 || config.DeviceOverrides[0].Key != "mouse" || *config.DeviceOverrides[0].PercentPrefix != "M:" {
+		t.Errorf("DeviceOverrides = %+v", config.DeviceOverrides)
+	}
+	if !reflect.DeepEqual(config.Devices, []string{"trackpad", "mouse"}) {
+		t.Errorf("Devices = %q", config.Devices)
+	}
+}
@@ -0,0 +1,259 @@
+package tmux
+
+import (
+	"path"
+	"strconv"
+	"strings"
+)
+
+// deviceOptionPrefix 设备覆盖选项的前缀，选项名为 @tpb_device_<key>_<field>
+const deviceOptionPrefix = "@tpb_device_"
+
+// deviceFields 可以按设备覆盖的字段，较长的后缀在前以便正确拆分 key
+var deviceFields = []string{
+	"not_show_threshold",
+	"medium_threshold",
+	"stress_threshold",
+	"prefix",
+	"suffix",
+	"match",
+}
+
+// DeviceOverride 一组按设备覆盖的配置，未设置的字段为 nil
+//
+// 设置了 Match 时按 glob 匹配设备名称（不区分大小写）；
+// 否则 Key 与设备类别（如 mouse）相同时匹配，
+// 或者将 Key 中的 "_" 换成空格后作为设备名称的子串匹配
+type DeviceOverride struct {
+	Key   string
+	Match string
+
+	PercentPrefix    *string
+	PercentSuffix    *string
+	StressThreshold  *int
+	MediumThreshold  *int
+	NotShowThreshold *int
+}
+
+// matchesClass 判断覆盖是否按类别匹配设备
+func (o *DeviceOverride) matchesClass(class string) bool {
+	return o.Match == "" && class != "" && o.Key == class
+}
+
+// matchesName 判断覆盖是否按名称匹配设备
+func (o *DeviceOverride) matchesName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	name = strings.ToLower(name)
+	if o.Match != "" {
+		ok, err := path.Match(strings.ToLower(o.Match), name)
+		return err == nil && ok
+	}
+	return strings.Contains(name, strings.ReplaceAll(strings.ToLower(o.Key), "_", " "))
+}
+
+// apply 将覆盖的字段写入配置
+func (o *DeviceOverride) apply(c *Config) {
+	if o.PercentPrefix != nil {
+		c.PercentPrefix = *o.PercentPrefix
+	}
+	if o.PercentSuffix != nil {
+		c.PercentSuffix = *o.PercentSuffix
+	}
+	if o.StressThreshold != nil {
+		c.StressThreshold = *o.StressThreshold
+	}
+	if o.MediumThreshold != nil {
+		c.MediumThreshold = *o.MediumThreshold
+	}
+	if o.NotShowThreshold != nil {
+		c.NotShowThreshold = *o.NotShowThreshold
+	}
+}
+
+// ForDevice 返回应用了设备覆盖后的配置副本，没有匹配的覆盖时返回原配置
+// 类别覆盖先应用，名称覆盖更具体，后应用
+func (c *Config) ForDevice(class, name string) *Config {
+	var matched []*DeviceOverride
+	for i := range c.DeviceOverrides {
+		if o := &c.DeviceOverrides[i]; o.matchesClass(class) {
+			matched = append(matched, o)
+		}
+	}
+	for i := range c.DeviceOverrides {
+		if o := &c.DeviceOverrides[i]; !o.matchesClass(class) && o.matchesName(name) {
+			matched = append(matched, o)
+		}
+	}
+	if len(matched) == 0 {
+		return c
+	}
+
+	resolved := *c
+	for _, o := range matched {
+		o.apply(&resolved)
+	}
+	return &resolved
+}
+
+// getDeviceOverrides 获取所有 @tpb_device_ 开头的选项并按 key 分组
+func (o *options) getDeviceOverrides() []DeviceOverride {
+	var names []string
+	for _, name := range o.names {
+		if strings.HasPrefix(name, deviceOptionPrefix) {
+			names = append(names, name)
+		}
+	}
+	return parseDeviceOverrides(names, func(option string) string {
+		return o.values[option]
+	})
+}
+
+// parseShowOptions 解析 show-options 的输出，返回指定前缀的选项名（按输出顺序）和去掉引号后的值
+// 与 show-option -v 一样去掉值两端的空白
+func parseShowOptions(output, prefix string) (names []string, values map[string]string) {
+	values = make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		name, value, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		names = append(names, name)
+		values[name] = strings.TrimSpace(unquoteOptionValue(value))
+	}
+	return names, values
+}
+
+// unquoteOptionValue 还原 show-options 输出中转义的值
+// 含空格或特殊字符的值会被单引号或双引号包围，引号内的特殊字符和控制字符用反斜杠转义
+func unquoteOptionValue(s string) string {
+	if n := len(s); n >= 2 && (s[0] == '"' || s[0] == '\'') && s[n-1] == s[0] {
+		s = s[1 : n-1]
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 == len(s) {
+			b.WriteByte(c)
+			continue
+		}
+
+		i++
+		switch c = s[i]; c {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '0', '1', '2', '3':
+			// 三位八进制表示的字节
+			if i+2 < len(s) {
+				if n, err := strconv.ParseUint(s[i:i+3], 8, 8); err == nil {
+					b.WriteByte(byte(n))
+					i += 2
+					continue
+				}
+			}
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// parseDeviceOverrides 将设备选项名解析为覆盖配置，按 key 第一次出现的顺序排列
+// 无法识别字段或值无效的选项会被忽略
+func parseDeviceOverrides(names []string, get func(option string) string) []DeviceOverride {
+	var overrides []DeviceOverride
+	index := make(map[string]int)
+
+	for _, name := range names {
+		rest := strings.TrimPrefix(name, deviceOptionPrefix)
+		var key, field string
+		for _, f := range deviceFields {
+			if k, ok := strings.CutSuffix(rest, "_"+f); ok && k != "" {
+				key, field = k, f
+				break
+			}
+		}
+		if key == "" {
+			continue
+		}
+
+		value := get(name)
+		if value == "" {
+			continue
+		}
+
+		i, ok := index[key]
+		if !ok {
+			i = len(overrides)
+			index[key] = i
+			overrides = append(overrides, DeviceOverride{Key: key})
+		}
+		o := &overrides[i]
+
+		switch field {
+		case "prefix":
+			o.PercentPrefix = &value
+		case "suffix":
+			o.PercentSuffix = &value
+		case "match":
+			o.Match = value
+		default:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			switch field {
+			case "stress_threshold":
+				o.StressThreshold = &n
+			case "medium_threshold":
+				o.MediumThreshold = &n
+			case "not_show_threshold":
+				o.NotShowThreshold = &n
+			}
+		}
+	}
+	return overrides
+}
+
+// MultiDevice 判断是否设置了设备筛选，设置后状态栏按顺序显示多个设备，否则只显示触摸板
+func (c *Config) MultiDevice() bool {
+	return len(c.Devices) > 0 || len(c.ExcludeDevices) > 0
+}
+
+// DeviceOrder 返回设备在显示顺序中的位置，shown 为 false 表示设备被筛选掉
+// 设备匹配 @tpb_exclude 中的任一项时隐藏；@tpb_devices 不为空时只显示匹配其中一项的设备，
+// 按第一个匹配项的位置排序；为空时所有设备的位置都为 0
+func (c *Config) DeviceOrder(class, name string) (order int, shown bool) {
+	for _, pattern := range c.ExcludeDevices {
+		if matchDevice(pattern, class, name) {
+			return 0, false
+		}
+	}
+	if len(c.Devices) == 0 {
+		return 0, true
+	}
+	for i, pattern := range c.Devices {
+		if matchDevice(pattern, class, name) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// matchDevice 判断筛选项是否匹配设备：与设备类别相同，或者按 glob 匹配设备名称（不区分大小写）
+func matchDevice(pattern, class, name string) bool {
+	pattern = strings.ToLower(pattern)
+	if class != "" && pattern == class {
+		return true
+	}
+	ok, err := path.Match(pattern, strings.ToLower(name))
+	return err == nil && ok
+}
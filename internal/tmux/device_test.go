@@ -0,0 +1,121 @@
+package tmux
+
+import "testing"
+
+func TestParseDeviceOverrides(t *testing.T) {
+	options := map[string]string{
+		"@tpb_device_mouse_prefix":                 "M:",
+		"@tpb_device_mouse_stress_threshold":       "40",
+		"@tpb_device_mx_master_not_show_threshold": "90",
+		"@tpb_device_mx_master_match":              "MX Master*",
+		"@tpb_device_keyboard_medium_threshold":    "abc",
+		"@tpb_device_unknown":                      "x",
+	}
+	names := []string{
+		"@tpb_device_mouse_prefix",
+		"@tpb_device_mouse_stress_threshold",
+		"@tpb_device_mx_master_not_show_threshold",
+		"@tpb_device_mx_master_match",
+		"@tpb_device_keyboard_medium_threshold",
+		"@tpb_device_unknown",
+	}
+
+	overrides := parseDeviceOverrides(names, func(option string) string { return options[option] })
+
+	// 无效的数值只忽略该字段，keyboard 仍然有一个空的覆盖
+	if len(overrides) != 3 {
+		t.Fatalf("应该有 3 个覆盖，实际 %d 个: %+v", len(overrides), overrides)
+	}
+
+	mouse := overrides[0]
+	if mouse.Key != "mouse" || mouse.PercentPrefix == nil || *mouse.PercentPrefix != "M:" ||
+		mouse.StressThreshold == nil || *mouse.StressThreshold != 40 {
+		t.Errorf("mouse = %+v", mouse)
+	}
+
+	mx := overrides[1]
+	if mx.Key != "mx_master" || mx.Match != "MX Master*" || mx.NotShowThreshold == nil || *mx.NotShowThreshold != 90 {
+		t.Errorf("mx_master = %+v", mx)
+	}
+
+	if kb := overrides[2]; kb.Key != "keyboard" || kb.MediumThreshold != nil {
+		t.Errorf("keyboard = %+v", kb)
+	}
+}
+
+func TestParseShowOptions(t *testing.T) {
+	output := `@tpb_theme nord
+@tpb_device_mouse_prefix "M: "
+@tpb_device_mx_master_match "MX Master*"
+@tpb_device_tablet_prefix "\$\"#"
+@tpb_device_keyboard_suffix ''
+@tpb_device_mouse_stress_threshold 40
+`
+	names, values := parseShowOptions(output, deviceOptionPrefix)
+
+	wantNames := []string{
+		"@tpb_device_mouse_prefix",
+		"@tpb_device_mx_master_match",
+		"@tpb_device_tablet_prefix",
+		"@tpb_device_keyboard_suffix",
+		"@tpb_device_mouse_stress_threshold",
+	}
+	if len(names) != len(wantNames) {
+		t.Fatalf("names = %q, want %q", names, wantNames)
+	}
+	for i := range wantNames {
+		if names[i] != wantNames[i] {
+			t.Errorf("names[%d] = %q, want %q", i, names[i], wantNames[i])
+		}
+	}
+
+	want := map[string]string{
+		"@tpb_device_mouse_prefix":           "M:",
+		"@tpb_device_mx_master_match":        "MX Master*",
+		"@tpb_device_tablet_prefix":          `$"#`,
+		"@tpb_device_keyboard_suffix":        "",
+		"@tpb_device_mouse_stress_threshold": "40",
+	}
+	for name, v := range want {
+		if values[name] != v {
+			t.Errorf("values[%q] = %q, want %q", name, values[name], v)
+		}
+	}
+}
+
+func TestConfigForDevice(t *testing.T) {
+	prefix, named := "M:", "Named:"
+	stress, notShow := 40, 90
+
+	config := &Config{PercentPrefix: "TP:", StressThreshold: 30, NotShowThreshold: 100}
+	config.DeviceOverrides = []DeviceOverride{
+		// 名称覆盖写在前面也应在类别覆盖之后应用
+		{Key: "magic_mouse", PercentPrefix: &named, NotShowThreshold: &notShow},
+		{Key: "mouse", PercentPrefix: &prefix, StressThreshold: &stress},
+	}
+
+	tests := []struct {
+		name, class, device string
+		prefix              string
+		stress, notShow     int
+	}{
+		{"没有匹配", "trackpad", "Magic Trackpad", "TP:", 30, 100},
+		{"类别匹配", "mouse", "MX Master 3", "M:", 40, 100},
+		{"类别和名称都匹配", "mouse", "Magic Mouse 2", "Named:", 40, 90},
+		{"名称不区分大小写", "mouse", "MAGIC MOUSE", "Named:", 40, 90},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := config.ForDevice(tt.class, tt.device)
+			if got.PercentPrefix != tt.prefix || got.StressThreshold != tt.stress || got.NotShowThreshold != tt.notShow {
+				t.Errorf("ForDevice(%q, %q) = prefix %q, stress %d, not show %d",
+					tt.class, tt.device, got.PercentPrefix, got.StressThreshold, got.NotShowThreshold)
+			}
+		})
+	}
+
+	// 不应修改原配置
+	if config.PercentPrefix != "TP:" || config.StressThreshold != 30 {
+		t.Errorf("原配置被修改: %+v", config)
+	}
+}
@@ -0,0 +1,303 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+	"github.com/akayj/tmux-touchpad-battery/internal/history"
+)
+
+// chartMetric 图表显示的指标
+type chartMetric int
+
+const (
+	chartBattery chartMetric = iota
+	chartCPU
+	chartGPU
+)
+
+// String 返回指标名称
+func (c chartMetric) String() string {
+	switch c {
+	case chartCPU:
+		return "CPU"
+	case chartGPU:
+		return "GPU"
+	}
+	return "Battery"
+}
+
+// 可切换的时间范围：电池来自历史记录（没有记录时使用运行期间的内存缓冲），CPU/GPU 来自内存缓冲
+var (
+	batteryRanges = []time.Duration{24 * time.Hour, 7 * 24 * time.Hour}
+	systemRanges  = []time.Duration{5 * time.Minute, 15 * time.Minute, time.Hour}
+)
+
+// systemBufferSize 内存缓冲容量，按 5 秒刷新一次可以覆盖最长的 1 小时范围
+const systemBufferSize = 720
+
+// chartHeight 图表高度（行）
+const chartHeight = 8
+
+// chartBars 图表柱顶使用的 1/8 方块字符
+var chartBars = []rune("▁▂▃▄▅▆▇")
+
+// chartPoint 图表中的一个数据点，Value 为 0-100 的百分比
+type chartPoint struct {
+	Time  time.Time
+	Value float64
+}
+
+// chartDevice 历史记录中的一个设备
+type chartDevice struct {
+	Key    string
+	Name   string
+	Points []chartPoint
+}
+
+// historyMsg 历史记录加载完成消息
+type historyMsg []chartDevice
+
+// ring 固定容量的环形缓冲，容量满后覆盖最旧的数据点
+type ring struct {
+	points []chartPoint
+	next   int
+	full   bool
+}
+
+// newRing 创建指定容量的环形缓冲
+func newRing(capacity int) *ring {
+	return &ring{points: make([]chartPoint, capacity)}
+}
+
+// push 追加一个数据点
+func (r *ring) push(p chartPoint) {
+	r.points[r.next] = p
+	r.next = (r.next + 1) % len(r.points)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// since 按时间顺序返回不早于 t 的数据点
+func (r *ring) since(t time.Time) []chartPoint {
+	ordered := r.points[:r.next]
+	if r.full {
+		ordered = append(append([]chartPoint{}, r.points[r.next:]...), r.points[:r.next]...)
+	}
+
+	var result []chartPoint
+	for _, p := range ordered {
+		if !p.Time.Before(t) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// loadHistory 从历史记录加载最近一周各外设的电量
+func loadHistory(store *history.Store) tea.Cmd {
+	return func() tea.Msg {
+		samples, err := store.Query(history.Query{Since: time.Now().Add(-batteryRanges[len(batteryRanges)-1])})
+		if err != nil {
+			return err
+		}
+
+		byKey := make(map[string]*chartDevice)
+		for _, s := range samples {
+			if s.Device == history.DeviceCPU {
+				continue
+			}
+			key := s.Serial
+			if key == "" {
+				key = s.Device
+			}
+			d, ok := byKey[key]
+			if !ok {
+				d = &chartDevice{Key: key}
+				byKey[key] = d
+			}
+			// 使用最新的设备名
+			d.Name = s.Device
+			d.Points = append(d.Points, chartPoint{Time: s.Time, Value: float64(s.Percent)})
+		}
+
+		devices := make(historyMsg, 0, len(byKey))
+		for _, d := range byKey {
+			devices = append(devices, *d)
+		}
+		sort.Slice(devices, func(i, j int) bool {
+			return devices[i].Name < devices[j].Name
+		})
+		return devices
+	}
+}
+
+// filterChartDevices 按 @tpb_devices 和 @tpb_exclude 筛选并排序图表设备，重新筛选后保持选中的设备
+func (m *Model) filterChartDevices() {
+	selected := ""
+	if len(m.devices) > 0 {
+		selected = m.devices[m.deviceIdx%len(m.devices)].Key
+	}
+
+	order := make(map[string]int, len(m.chartAll))
+	m.devices = m.devices[:0]
+	for _, d := range m.chartAll {
+		class := (&battery.BatteryInfo{Name: d.Name}).Class()
+		i, shown := m.config.DeviceOrder(class, d.Name)
+		if !shown {
+			if !m.showHidden {
+				continue
+			}
+			// 被筛选掉的设备排在最后
+			i = len(m.config.Devices)
+		}
+		order[d.Key] = i
+		m.devices = append(m.devices, d)
+	}
+	sort.SliceStable(m.devices, func(i, j int) bool {
+		return order[m.devices[i].Key] < order[m.devices[j].Key]
+	})
+
+	m.deviceIdx = 0
+	for i, d := range m.devices {
+		if d.Key == selected {
+			m.deviceIdx = i
+		}
+	}
+}
+
+// renderChart 将 [start, end] 内的数据点按列取平均，渲染为带坐标轴的柱状图
+func renderChart(points []chartPoint, start, end time.Time, width, height int) []string {
+	sums := make([]float64, width)
+	counts := make([]int, width)
+	span := end.Sub(start)
+	for _, p := range points {
+		if p.Time.Before(start) || p.Time.After(end) || span <= 0 {
+			continue
+		}
+		col := min(int(float64(p.Time.Sub(start))/float64(span)*float64(width)), width-1)
+		sums[col] += p.Value
+		counts[col]++
+	}
+
+	lines := make([]string, 0, height+1)
+	for row := height - 1; row >= 0; row-- {
+		var label string
+		switch row {
+		case height - 1:
+			label = "100%┤"
+		case height / 2:
+			label = " 50%┤"
+		case 0:
+			label = "  0%┤"
+		default:
+			label = "    │"
+		}
+
+		var b strings.Builder
+		b.WriteString(label)
+		for col := 0; col < width; col++ {
+			if counts[col] == 0 {
+				b.WriteString(" ")
+				continue
+			}
+			// 以 1/8 行为单位计算柱高
+			value := min(max(sums[col]/float64(counts[col]), 0), 100)
+			filled := int(value/100*float64(height*8)+0.5) - row*8
+			switch {
+			case filled >= 8:
+				b.WriteString("█")
+			case filled <= 0:
+				b.WriteString(" ")
+			default:
+				b.WriteRune(chartBars[filled-1])
+			}
+		}
+		lines = append(lines, b.String())
+	}
+
+	lines = append(lines, "    └"+strings.Repeat("─", width))
+	return lines
+}
+
+// formatRange 返回时间范围的简短文本，如 5m、1h、7d
+func formatRange(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dm", int(d.Minutes()))
+}
+
+// chartRange 返回当前指标的时间范围
+func (m *Model) chartRange() time.Duration {
+	if m.chartMetric == chartBattery {
+		return batteryRanges[m.batteryRange%len(batteryRanges)]
+	}
+	return systemRanges[m.systemRange%len(systemRanges)]
+}
+
+// chartView 渲染图表面板
+func (m *Model) chartView() string {
+	now := time.Now()
+	rng := m.chartRange()
+
+	var (
+		title  string
+		points []chartPoint
+		fg     string
+	)
+	switch m.chartMetric {
+	case chartBattery:
+		// 没有历史记录时使用运行期间采集的触摸板电量
+		title = "Battery: Touchpad (live)"
+		fg = m.config.ColorHigh
+		points = m.batteryLive.since(now.Add(-rng))
+		if len(m.devices) > 0 {
+			d := m.devices[m.deviceIdx%len(m.devices)]
+			title = fmt.Sprintf("Battery: %s (%d/%d)", d.Name, m.deviceIdx%len(m.devices)+1, len(m.devices))
+			points = d.Points
+		}
+	case chartCPU:
+		title = m.chartMetric.String()
+		fg = m.config.ColorCPU
+		points = m.cpuHistory.since(now.Add(-rng))
+	case chartGPU:
+		title = m.chartMetric.String()
+		fg = m.config.ColorGPU
+		points = m.gpuHistory.since(now.Add(-rng))
+	}
+
+	width := 60
+	if m.width > 0 {
+		// 扣除坐标轴标签和边框
+		width = max(10, min(m.width-12, 120))
+	}
+
+	header := lipgloss.NewStyle().Bold(true).Render(title) +
+		lipgloss.NewStyle().Foreground(m.color(m.config.ColorDetail)).Render("  last "+formatRange(rng))
+
+	var body string
+	if len(points) == 0 {
+		body = lipgloss.NewStyle().Foreground(m.color(m.config.ColorMuted)).Render("No data yet")
+	} else {
+		chart := renderChart(points, now.Add(-rng), now, width, chartHeight)
+		body = lipgloss.NewStyle().Foreground(m.color(fg)).Render(strings.Join(chart, "\n"))
+		axis := fmt.Sprintf("     -%s%s", formatRange(rng), strings.Repeat(" ", max(1, width-len(formatRange(rng))-4))+"now")
+		body += "\n" + lipgloss.NewStyle().Foreground(m.color(m.config.ColorMuted)).Render(axis)
+	}
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		Padding(0, 1).
+		Render(header + "\n\n" + body)
+}
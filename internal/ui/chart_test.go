@@ -0,0 +1,91 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/tmux"
+)
+
+func TestRing(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := newRing(3)
+
+	for i := 0; i < 5; i++ {
+		r.push(chartPoint{Time: base.Add(time.Duration(i) * time.Minute), Value: float64(i)})
+	}
+
+	points := r.since(base)
+	if len(points) != 3 {
+		t.Fatalf("容量为 3 时应该保留 3 个数据点，实际 %d 个", len(points))
+	}
+	for i, p := range points {
+		if p.Value != float64(i+2) {
+			t.Errorf("数据点顺序错误: %+v", points)
+			break
+		}
+	}
+
+	if points := r.since(base.Add(4 * time.Minute)); len(points) != 1 {
+		t.Errorf("按时间过滤错误: %+v", points)
+	}
+}
+
+func TestRenderChart(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(4 * time.Minute)
+	points := []chartPoint{
+		{Time: start, Value: 100},
+		{Time: start.Add(time.Minute), Value: 50},
+		{Time: start.Add(3 * time.Minute), Value: 0},
+	}
+
+	lines := renderChart(points, start, end, 4, 2)
+	want := []string{
+		"100%┤█   ",
+		"  0%┤██  ",
+		"    └────",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("图表渲染错误:\n%s\n期望:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestFilterChartDevices(t *testing.T) {
+	m := &Model{config: &tmux.Config{
+		Devices:        []string{"keyboard", "trackpad"},
+		ExcludeDevices: []string{"AirPods*"},
+	}}
+	m.chartAll = []chartDevice{
+		{Key: "A1", Name: "AirPods Pro"},
+		{Key: "K1", Name: "Magic Keyboard"},
+		{Key: "M1", Name: "Magic Mouse"},
+		{Key: "T1", Name: "Magic Trackpad"},
+	}
+
+	m.filterChartDevices()
+	if got := chartKeys(m.devices); got != "K1,T1" {
+		t.Errorf("筛选后的设备 = %s, want K1,T1", got)
+	}
+
+	// 切换显示隐藏设备后保持选中的设备
+	m.deviceIdx = 1
+	m.showHidden = true
+	m.filterChartDevices()
+	if got := chartKeys(m.devices); got != "K1,T1,A1,M1" {
+		t.Errorf("显示隐藏设备后 = %s, want K1,T1,A1,M1", got)
+	}
+	if m.devices[m.deviceIdx].Key != "T1" {
+		t.Errorf("选中的设备 = %s, want T1", m.devices[m.deviceIdx].Key)
+	}
+}
+
+// chartKeys 返回逗号分隔的设备标识
+func chartKeys(devices []chartDevice) string {
+	keys := make([]string, 0, len(devices))
+	for _, d := range devices {
+		keys = append(keys, d.Key)
+	}
+	return strings.Join(keys, ",")
+}
@@ -2,15 +2,22 @@ package ui
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
 	"github.com/akayj/tmux-touchpad-battery/internal/battery"
+	"github.com/akayj/tmux-touchpad-battery/internal/color"
 	"github.com/akayj/tmux-touchpad-battery/internal/display"
+	"github.com/akayj/tmux-touchpad-battery/internal/events"
+	"github.com/akayj/tmux-touchpad-battery/internal/history"
+	"github.com/akayj/tmux-touchpad-battery/internal/presence"
+	"github.com/akayj/tmux-touchpad-battery/internal/snapshot"
 	"github.com/akayj/tmux-touchpad-battery/internal/system"
 	"github.com/akayj/tmux-touchpad-battery/internal/tmux"
+	"github.com/akayj/tmux-touchpad-battery/internal/webhook"
 )
 
 // Model 表示 TUI 模型
@@ -20,8 +27,50 @@ type Model struct {
 	formatter    display.Formatter
 	sysFormatter display.Formatter
 	config       *tmux.Config
+	configErrs   []error
 	err          error
 	quitting     bool
+
+	// 图表相关状态
+	store        *history.Store
+	showChart    bool
+	chartMetric  chartMetric
+	batteryRange int
+	systemRange  int
+	devices      []chartDevice
+	deviceIdx    int
+	// chartAll 筛选前的历史记录设备
+	chartAll    []chartDevice
+	batteryLive *ring
+	cpuHistory  *ring
+	gpuHistory  *ring
+	width       int
+
+	// watcher 运行事件钩子和 webhook，都没有配置时为 nil
+	watcher *events.Watcher
+
+	// 触摸板断开时最后一次出现的状态
+	tracker  *presence.Tracker
+	lastSeen *presence.Record
+
+	// 所有外设，按 @tpb_devices 和 @tpb_exclude 筛选后显示，showHidden 时同时显示被筛选掉的设备
+	deviceList []*battery.BatteryInfo
+	showHidden bool
+
+	// 提供者支持推送时（如 Linux 上的 UPower）的设备变化通知，不支持时为 nil；退出时关闭 stop
+	changes <-chan struct{}
+	stop    chan struct{}
+}
+
+// devicesMsg 外设列表更新消息
+type devicesMsg []*battery.BatteryInfo
+
+// devicesChangedMsg 提供者推送的设备变化消息
+type devicesChangedMsg struct{}
+
+// lastSeenMsg 断开设备的最后状态加载完成消息
+type lastSeenMsg struct {
+	record *presence.Record
 }
 
 // tickMsg 定时更新消息
@@ -30,22 +79,59 @@ type tickMsg time.Time
 // NewModel 创建新的 TUI 模型
 func NewModel() *Model {
 	config := tmux.GetConfig()
+	themeErr := display.ApplyTheme(config)
 	batteryFormatter := display.NewBatteryFormatter(config)
 	systemFormatter := display.NewSystemFormatter(config)
 
+	configErrs := batteryFormatter.Errors()
+	if themeErr != nil {
+		configErrs = append([]error{themeErr}, configErrs...)
+	}
+
+	// 历史记录不可用时图表只显示运行期间的 CPU/GPU
+	store, _ := history.Open()
+
+	// 与 tmux 模式和常驻模式共享事件状态，同一事件只触发一次
+	var handlers []events.Handler
+	if wh, err := webhook.FromConfig(config); err != nil {
+		configErrs = append(configErrs, err)
+	} else if wh != nil {
+		handlers = append(handlers, wh)
+	}
+	var watcher *events.Watcher
+	if path, err := events.StatePath(); err == nil {
+		watcher = events.NewWatcher(config, path, handlers...)
+	}
+
+	tracker, _ := presence.Open()
+
+	// 不支持推送时只按定时器刷新
+	stop := make(chan struct{})
+	changes, _ := battery.WatchDevices(stop)
+
 	return &Model{
 		config:       config,
+		configErrs:   configErrs,
 		formatter:    batteryFormatter,
 		sysFormatter: systemFormatter,
+		store:        store,
+		batteryLive:  newRing(systemBufferSize),
+		cpuHistory:   newRing(systemBufferSize),
+		gpuHistory:   newRing(systemBufferSize),
+		watcher:      watcher,
+		tracker:      tracker,
+		changes:      changes,
+		stop:         stop,
 	}
 }
 
 // Init 初始化模型
 func (m *Model) Init() tea.Cmd {
 	return tea.Batch(
-		m.updateBattery(),
+		m.updateDevices(),
 		m.updateSystemInfo(),
 		m.tick(),
+		m.waitForChange(),
 	)
 }
 
@@ -56,30 +142,95 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch msg.String() {
 		case "q", "ctrl+c":
 			m.quitting = true
+			close(m.stop)
 			return m, tea.Quit
 		case "r":
 			// 手动刷新
 			return m, tea.Batch(
-				m.updateBattery(),
+				m.updateDevices(),
 				m.updateSystemInfo(),
+				m.updateHistory(),
 			)
+		case "c":
+			// 显示/隐藏图表
+			m.showChart = !m.showChart
+			return m, m.updateHistory()
+		case "tab":
+			// 切换图表指标
+			m.chartMetric = (m.chartMetric + 1) % 3
+		case "t":
+			// 切换时间范围
+			if m.chartMetric == chartBattery {
+				m.batteryRange = (m.batteryRange + 1) % len(batteryRanges)
+			} else {
+				m.systemRange = (m.systemRange + 1) % len(systemRanges)
+			}
+		case "d":
+			// 下一个设备
+			if len(m.devices) > 0 {
+				m.deviceIdx = (m.deviceIdx + 1) % len(m.devices)
+			}
+		case "D":
+			// 上一个设备
+			if len(m.devices) > 0 {
+				m.deviceIdx = (m.deviceIdx + len(m.devices) - 1) % len(m.devices)
+			}
+		case "h":
+			// 显示/隐藏被筛选掉的设备
+			m.showHidden = !m.showHidden
+			m.filterChartDevices()
 		}
 
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+
 	case tickMsg:
 		// 定时更新
 		return m, tea.Batch(
-			m.updateBattery(),
-			m.updateSystemInfo(),
+			m.sample(),
+			m.updateHistory(),
 			m.tick(),
 		)
 
-	case *battery.BatteryInfo:
-		m.batteryInfo = msg
+	case *snapshot.Snapshot:
+		// 外设列表、触摸板电量、系统信息和事件钩子共用同一次采样
 		m.err = nil
+		var cmd tea.Cmd
+		if msg.DevicesErr != nil {
+			m.err = msg.DevicesErr
+		} else {
+			m.deviceList = msg.Devices
+			cmd = m.setBattery(battery.FindTouchpad(msg.Devices))
+		}
+		if msg.SystemErr != nil {
+			m.err = msg.SystemErr
+		} else {
+			m.setSystemInfo(msg.System)
+		}
+		return m, tea.Batch(cmd, m.observeEvents(msg))
+
+	case historyMsg:
+		m.chartAll = msg
+		m.filterChartDevices()
+
+	case devicesMsg:
+		m.deviceList = msg
+		m.err = nil
+		return m, m.setBattery(battery.FindTouchpad(msg))
+
+	case devicesChangedMsg:
+		// 设备变化时立即刷新，不等待定时器
+		return m, tea.Batch(
+			m.updateDevices(),
+			m.waitForChange(),
+		)
+
+	case lastSeenMsg:
+		m.lastSeen = msg.record
 
 	case *system.SystemInfo:
-		m.systemInfo = msg
 		m.err = nil
+		m.setSystemInfo(msg)
 
 	case error:
 		m.err = msg
@@ -99,8 +250,8 @@ func (m *Model) View() string {
 	// 标题
 	title := lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("#FAFAFA")).
-		Background(lipgloss.Color("#7D56F4")).
+		Foreground(m.color(m.config.ColorTitleFg)).
+		Background(m.color(m.config.ColorTitleBg)).
 		Padding(0, 1).
 		Render("Tmux Touchpad Battery Monitor")
 
@@ -109,16 +260,27 @@ func (m *Model) View() string {
 	// 错误信息
 	if m.err != nil {
 		errorStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FF0000")).
+			Foreground(m.color(m.config.ColorError)).
 			Bold(true)
 		content += errorStyle.Render("Error: "+m.err.Error()) + "\n\n"
 	}
 
+	// 配置错误
+	if len(m.configErrs) > 0 {
+		warnStyle := lipgloss.NewStyle().
+			Foreground(m.color(m.config.ColorWarning))
+		for _, err := range m.configErrs {
+			content += warnStyle.Render("Config: "+err.Error()) + "\n"
+		}
+		content += "\n"
+	}
+
 	// 电池信息
 	if m.batteryInfo != nil {
 		// 设置电池信息并格式化
 		if bf, ok := m.formatter.(*display.BatteryFormatter); ok {
 			bf.SetBatteryInfo(m.batteryInfo)
+			bf.SetLastSeen(m.lastSeen)
 		}
 		batteryDisplay := m.formatter.FormatWithStyle()
 		content += "Battery Status: " + batteryDisplay + "\n\n"
@@ -126,15 +288,20 @@ func (m *Model) View() string {
 		// 详细信息
 		if m.batteryInfo.Available {
 			detailStyle := lipgloss.NewStyle().
-				Foreground(lipgloss.Color("#888888"))
+				Foreground(m.color(m.config.ColorDetail))
 
 			details := ""
 			details += detailStyle.Render("Percentage: ") +
 				lipgloss.NewStyle().Bold(true).Render(fmt.Sprintf("%d%%", m.batteryInfo.Percentage)) + "\n"
 
 			chargingText := "No"
-			if m.batteryInfo.IsCharging {
+			switch m.batteryInfo.ChargeState() {
+			case battery.StateCharging:
 				chargingText = "Yes ⚡"
+			case battery.StateFull:
+				chargingText = "Full, plugged in"
+			case battery.StateNotCharging:
+				chargingText = "Plugged in, not charging"
 			}
 			details += detailStyle.Render("Charging: ") +
 				lipgloss.NewStyle().Bold(true).Render(chargingText) + "\n"
@@ -143,10 +310,15 @@ func (m *Model) View() string {
 		}
 	} else {
 		content += lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#888888")).
+			Foreground(m.color(m.config.ColorDetail)).
 			Render("Loading battery information...") + "\n\n"
 	}
 
+	// 外设列表
+	if len(m.deviceList) > 0 {
+		content += m.devicesView() + "\n"
+	}
+
 	// 系统信息
 	if m.systemInfo != nil {
 		// 设置系统信息并格式化
@@ -160,7 +332,7 @@ func (m *Model) View() string {
 			// 详细信息
 			if m.systemInfo.Available {
 				detailStyle := lipgloss.NewStyle().
-					Foreground(lipgloss.Color("#888888"))
+					Foreground(m.color(m.config.ColorDetail))
 
 				details := ""
 				details += detailStyle.Render("CPU Usage: ") +
@@ -178,13 +350,18 @@ func (m *Model) View() string {
 		}
 	} else {
 		content += lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#888888")).
+			Foreground(m.color(m.config.ColorDetail)).
 			Render("Loading system information...") + "\n\n"
 	}
 
+	// 图表
+	if m.showChart {
+		content += m.chartView() + "\n\n"
+	}
+
 	// 配置信息
 	configStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#666666")).
+		Foreground(m.color(m.config.ColorMuted)).
 		Border(lipgloss.RoundedBorder()).
 		Padding(1)
 
@@ -198,23 +375,77 @@ func (m *Model) View() string {
 
 	// 帮助信息
 	helpStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#666666"))
-	content += helpStyle.Render("Press 'r' to refresh, 'q' to quit")
+		Foreground(m.color(m.config.ColorMuted))
+	help := "Press 'r' to refresh, 'c' to toggle chart, 'h' to toggle hidden devices, 'q' to quit"
+	if m.showChart {
+		help += "\nChart: 'tab' switch metric, 't' time range, 'd'/'D' next/prev device"
+	}
+	content += helpStyle.Render(help)
 
 	return content
 }
 
-// updateBattery 更新电池信息
-func (m *Model) updateBattery() tea.Cmd {
+// color 将 tmux 颜色配置转换为 lipgloss 颜色
+func (m *Model) color(value string) lipgloss.TerminalColor {
+	return color.MustParse(value).Lipgloss()
+}
+
+// updateDevices 更新外设列表和其中的触摸板电量，获取失败时保持上一次的列表
+func (m *Model) updateDevices() tea.Cmd {
 	return func() tea.Msg {
-		info, err := battery.GetTouchpadBatteryInfo()
+		devices, err := battery.GetDevices()
 		if err != nil {
 			return err
 		}
-		return info
+		return devicesMsg(devices)
 	}
 }
 
+// setBattery 设置触摸板电池信息，断开时加载它最后一次出现的状态
+func (m *Model) setBattery(info *battery.BatteryInfo) tea.Cmd {
+	m.batteryInfo = info
+	if !info.Available {
+		return m.updateLastSeen()
+	}
+	m.batteryLive.push(chartPoint{Time: time.Now(), Value: float64(info.Percentage)})
+	m.lastSeen = nil
+	return nil
+}
+
+// devicesView 按筛选后的顺序渲染外设列表，被筛选掉的设备以次要颜色显示
+func (m *Model) devicesView() string {
+	detailStyle := lipgloss.NewStyle().Foreground(m.color(m.config.ColorDetail))
+	mutedStyle := lipgloss.NewStyle().Foreground(m.color(m.config.ColorMuted))
+
+	shown, hidden := display.FilterDevices(m.config, m.deviceList)
+
+	content := detailStyle.Render("Devices:") + "\n"
+	for _, d := range shown {
+		content += "  " + deviceLine(d) + "\n"
+	}
+	if m.showHidden {
+		for _, d := range hidden {
+			content += "  " + mutedStyle.Render(deviceLine(d)+" (hidden)") + "\n"
+		}
+	} else if len(hidden) > 0 {
+		content += "  " + mutedStyle.Render(fmt.Sprintf("%d hidden, press 'h' to show", len(hidden))) + "\n"
+	}
+	return content
+}
+
+// deviceLine 返回外设的一行摘要
+func deviceLine(d *battery.BatteryInfo) string {
+	name := d.Name
+	if name == "" {
+		name = "Unknown device"
+	}
+	line := fmt.Sprintf("%s: %d%%", name, d.Percentage)
+	if state := d.ChargeState(); state.PluggedIn() {
+		line += " (" + strings.ReplaceAll(state.String(), "_", " ") + ")"
+	}
+	return line
+}
+
 // updateSystemInfo 更新系统信息
 func (m *Model) updateSystemInfo() tea.Cmd {
 	return func() tea.Msg {
@@ -226,6 +457,71 @@ func (m *Model) updateSystemInfo() tea.Cmd {
 	}
 }
 
+// setSystemInfo 设置系统信息并记录 CPU/GPU 使用率
+func (m *Model) setSystemInfo(info *system.SystemInfo) {
+	m.systemInfo = info
+	if info.Available {
+		now := time.Now()
+		m.cpuHistory.push(chartPoint{Time: now, Value: info.CPUUsage})
+		if info.GPUErr == nil {
+			m.gpuHistory.push(chartPoint{Time: now, Value: info.GPUUsage})
+		}
+	}
+}
+
+// updateLastSeen 触摸板断开时加载它最后一次出现的状态
+func (m *Model) updateLastSeen() tea.Cmd {
+	if m.tracker == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		r, err := m.tracker.LastDisconnected(battery.ClassTrackpad)
+		if err != nil {
+			return lastSeenMsg{}
+		}
+		return lastSeenMsg{record: r}
+	}
+}
+
+// updateHistory 图表显示时重新加载历史记录
+func (m *Model) updateHistory() tea.Cmd {
+	if !m.showChart || m.store == nil {
+		return nil
+	}
+	return loadHistory(m.store)
+}
+
+// sample 定时采集外设和系统信息
+func (m *Model) sample() tea.Cmd {
+	return func() tea.Msg {
+		return snapshot.Collect()
+	}
+}
+
+// observeEvents 用定时采样的结果运行事件钩子，钩子的错误不影响界面
+func (m *Model) observeEvents(s *snapshot.Snapshot) tea.Cmd {
+	if m.watcher == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		_ = m.watcher.Handle(s)
+		return nil
+	}
+}
+
+// waitForChange 等待提供者推送的下一次设备变化，推送中断后不再等待
+func (m *Model) waitForChange() tea.Cmd {
+	if m.changes == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		if _, ok := <-m.changes; !ok {
+			return nil
+		}
+		return devicesChangedMsg{}
+	}
+}
+
 // tick 定时器
 func (m *Model) tick() tea.Cmd {
 	return tea.Tick(time.Second*5, func(t time.Time) tea.Msg {
@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/state"
+)
+
+// loadQueue 读取待补发的请求，文件不存在时返回空队列
+func (n *Notifier) loadQueue() ([]entry, error) {
+	if n.QueuePath == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(n.QueuePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var queue []entry
+	if err := json.Unmarshal(data, &queue); err != nil {
+		return nil, err
+	}
+	return queue, nil
+}
+
+// saveQueue 写回未送达的请求，队列为空时删除文件
+func (n *Notifier) saveQueue(queue []entry) error {
+	if n.QueuePath == "" {
+		return nil
+	}
+	if len(queue) == 0 {
+		err := os.Remove(n.QueuePath)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	data, err := json.Marshal(queue)
+	if err != nil {
+		return err
+	}
+
+	return state.WriteFile(n.QueuePath, data)
+}
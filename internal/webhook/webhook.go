@@ -0,0 +1,318 @@
+// Package webhook 在电池事件发生时向配置的 URL 发送 JSON 请求
+// 发送失败时按退避间隔重试，仍然失败的请求保存在本地队列中，下次发送时补发
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/events"
+	"github.com/akayj/tmux-touchpad-battery/internal/state"
+	"github.com/akayj/tmux-touchpad-battery/internal/tmux"
+)
+
+// 请求体格式
+const (
+	// FormatGeneric 包含完整事件数据的 JSON
+	FormatGeneric = "generic"
+	// FormatSlack Slack incoming webhook 格式，只有 text 字段
+	FormatSlack = "slack"
+)
+
+// 默认参数
+const (
+	DefaultAttempts = 3
+	DefaultBackoff  = time.Second
+	DefaultTimeout  = 10 * time.Second
+	DefaultMaxAge   = 24 * time.Hour
+	DefaultMaxQueue = 100
+
+	// RedrawDeadline tmux 状态栏重绘时一次发送（包括补发队列）的总时限
+	RedrawDeadline = 2 * time.Second
+)
+
+// QueueFile 状态目录下保存待补发请求的文件名
+const QueueFile = "webhook-queue.json"
+
+// DefaultEvents 默认发送的事件类型
+var DefaultEvents = []string{events.BatteryLow, events.BatteryCritical}
+
+// Notifier 实现 events.Handler，将事件 POST 到配置的 URL，失败的请求在之后的采样中补发
+type Notifier struct {
+	URLs   []string
+	Format string
+	// Events 发送的事件类型，为空时使用 DefaultEvents
+	Events []string
+
+	Client *http.Client
+	// Attempts 每次发送的最大尝试次数，Backoff 为首次重试前的等待时间，之后每次加倍
+	Attempts int
+	Backoff  time.Duration
+	// Deadline 一次发送（包括补发队列）的总时限，0 表示不限制；超时后未发送的请求留在队列中
+	Deadline time.Duration
+
+	// QueuePath 为空时不保存失败的请求
+	QueuePath string
+	// MaxAge 超过该时间仍未送达的请求被丢弃
+	MaxAge   time.Duration
+	MaxQueue int
+
+	// sleep 和 now 可在测试中替换
+	sleep func(time.Duration)
+	now   func() time.Time
+
+	mu sync.Mutex
+}
+
+// entry 一个待发送的请求
+type entry struct {
+	URL     string          `json:"url"`
+	Payload json.RawMessage `json:"payload"`
+	Created time.Time       `json:"created"`
+}
+
+// genericPayload generic 格式的请求体
+type genericPayload struct {
+	events.Event
+	Message string `json:"message"`
+}
+
+// slackPayload Slack incoming webhook 格式的请求体
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+// permanentError 重试也不会成功的错误，如 4xx 响应
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// New 创建新的 webhook 通知
+func New(urls []string, format string) (*Notifier, error) {
+	switch format {
+	case "":
+		format = FormatGeneric
+	case FormatGeneric, FormatSlack:
+	default:
+		return nil, fmt.Errorf("无效的 webhook 格式: %q", format)
+	}
+
+	return &Notifier{
+		URLs:     urls,
+		Format:   format,
+		Client:   &http.Client{Timeout: DefaultTimeout},
+		Attempts: DefaultAttempts,
+		Backoff:  DefaultBackoff,
+		MaxAge:   DefaultMaxAge,
+		MaxQueue: DefaultMaxQueue,
+	}, nil
+}
+
+// FromConfig 根据 tmux 配置创建 webhook 通知，队列保存在状态目录中
+// 没有配置 URL 时返回 nil
+func FromConfig(config *tmux.Config) (*Notifier, error) {
+	urls := splitList(config.WebhookURL)
+	if len(urls) == 0 {
+		return nil, nil
+	}
+
+	n, err := New(urls, config.WebhookFormat)
+	if err != nil {
+		return nil, err
+	}
+	if evs := splitList(config.WebhookEvents); len(evs) > 0 {
+		n.Events = evs
+	}
+
+	dir, err := state.Dir()
+	if err != nil {
+		return nil, err
+	}
+	n.QueuePath = filepath.Join(dir, QueueFile)
+	return n, nil
+}
+
+// splitList 拆分逗号或空白分隔的列表
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+}
+
+// Wants 判断是否发送该类型的事件
+func (n *Notifier) Wants(typ string) bool {
+	wanted := n.Events
+	if len(wanted) == 0 {
+		wanted = DefaultEvents
+	}
+	return slices.Contains(wanted, typ) || slices.Contains(wanted, "*")
+}
+
+// HandleEvent 实现 events.Handler
+// 先补发队列中的请求再发送新事件，同一 URL 的请求按顺序送达
+func (n *Notifier) HandleEvent(ev events.Event) error {
+	var pending []entry
+	if n.Wants(ev.Type) {
+		payload, err := n.payload(ev)
+		if err != nil {
+			return err
+		}
+		for _, url := range n.URLs {
+			pending = append(pending, entry{URL: url, Payload: payload, Created: n.clock()})
+		}
+	}
+	return n.deliver(pending)
+}
+
+// Flush 实现 events.Flusher，补发队列中的请求
+func (n *Notifier) Flush() error {
+	return n.deliver(nil)
+}
+
+// deliver 依次发送队列中的请求和新请求，未送达的请求写回队列
+func (n *Notifier) deliver(fresh []entry) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var errs []error
+	queue, err := n.loadQueue()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(queue) == 0 && len(fresh) == 0 {
+		return errors.Join(errs...)
+	}
+	queue = append(queue, fresh...)
+
+	ctx := context.Background()
+	if n.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.Deadline)
+		defer cancel()
+	}
+
+	var (
+		remaining []entry
+		down      = make(map[string]bool)
+		now       = n.clock()
+	)
+	for _, e := range queue {
+		if n.MaxAge > 0 && now.Sub(e.Created) > n.MaxAge {
+			errs = append(errs, fmt.Errorf("%s: 超过 %s 仍未送达，已丢弃", e.URL, n.MaxAge))
+			continue
+		}
+		// 同一 URL 前面的请求失败后不再尝试，保持顺序；超过总时限后不再发送
+		if down[e.URL] || ctx.Err() != nil {
+			remaining = append(remaining, e)
+			continue
+		}
+
+		err := n.send(ctx, e)
+		var perm *permanentError
+		switch {
+		case err == nil:
+		case errors.As(err, &perm):
+			errs = append(errs, err)
+		default:
+			down[e.URL] = true
+			remaining = append(remaining, e)
+			errs = append(errs, err)
+		}
+	}
+
+	if n.MaxQueue > 0 && len(remaining) > n.MaxQueue {
+		remaining = remaining[len(remaining)-n.MaxQueue:]
+	}
+	if err := n.saveQueue(remaining); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// send 发送单个请求，临时错误按指数退避重试
+func (n *Notifier) send(ctx context.Context, e entry) error {
+	attempts := max(n.Attempts, 1)
+	backoff := n.Backoff
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 && backoff > 0 {
+			n.wait(backoff)
+			backoff *= 2
+		}
+		err = n.post(ctx, e)
+		var perm *permanentError
+		if err == nil || errors.As(err, &perm) {
+			return err
+		}
+	}
+	return err
+}
+
+// post 发送一次 POST 请求
+func (n *Notifier) post(ctx context.Context, e entry) error {
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(e.Payload))
+	if err != nil {
+		return fmt.Errorf("%s: %w", e.URL, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", e.URL, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestTimeout,
+		resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode >= 500:
+		return fmt.Errorf("%s: %s", e.URL, resp.Status)
+	}
+	return &permanentError{fmt.Errorf("%s: %s", e.URL, resp.Status)}
+}
+
+// payload 按配置的格式生成请求体
+func (n *Notifier) payload(ev events.Event) ([]byte, error) {
+	if n.Format == FormatSlack {
+		return json.Marshal(slackPayload{Text: ev.Message()})
+	}
+	return json.Marshal(genericPayload{Event: ev, Message: ev.Message()})
+}
+
+// wait 等待重试
+func (n *Notifier) wait(d time.Duration) {
+	if n.sleep != nil {
+		n.sleep(d)
+		return
+	}
+	time.Sleep(d)
+}
+
+// clock 返回当前时间
+func (n *Notifier) clock() time.Time {
+	if n.now != nil {
+		return n.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,251 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/akayj/tmux-touchpad-battery/internal/events"
+)
+
+// server 记录收到的请求体，按 status 依次返回状态码，用完后返回 200
+type server struct {
+	mu     sync.Mutex
+	status []int
+	bodies [][]byte
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code := http.StatusOK
+	if len(s.status) > 0 {
+		code, s.status = s.status[0], s.status[1:]
+	}
+	if code == http.StatusOK {
+		s.bodies = append(s.bodies, body)
+	}
+	w.WriteHeader(code)
+}
+
+func newNotifier(t *testing.T, url, format string) *Notifier {
+	t.Helper()
+	n, err := New([]string{url}, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.sleep = func(time.Duration) {}
+	n.QueuePath = filepath.Join(t.TempDir(), QueueFile)
+	return n
+}
+
+func lowEvent(percent int) events.Event {
+	return events.Event{
+		Type:   events.BatteryLow,
+		Time:   time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+		Device: &events.Device{Name: "Magic Trackpad", Serial: "ABC", Percentage: percent},
+	}
+}
+
+func TestGenericPayload(t *testing.T) {
+	srv := &server{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	n := newNotifier(t, ts.URL, FormatGeneric)
+	if err := n.HandleEvent(lowEvent(18)); err != nil {
+		t.Fatal(err)
+	}
+	if len(srv.bodies) != 1 {
+		t.Fatalf("收到 %d 个请求, want 1", len(srv.bodies))
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(srv.bodies[0], &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["event"] != events.BatteryLow || got["message"] != "Magic Trackpad battery low (18%)" {
+		t.Errorf("payload = %s", srv.bodies[0])
+	}
+	if d, ok := got["device"].(map[string]any); !ok || d["percentage"] != float64(18) {
+		t.Errorf("device = %v", got["device"])
+	}
+}
+
+func TestSlackPayload(t *testing.T) {
+	srv := &server{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	n := newNotifier(t, ts.URL, FormatSlack)
+	if err := n.HandleEvent(lowEvent(18)); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"text":"Magic Trackpad battery low (18%)"}`; len(srv.bodies) != 1 || string(srv.bodies[0]) != want {
+		t.Errorf("payload = %q, want %q", srv.bodies, want)
+	}
+}
+
+func TestInvalidFormat(t *testing.T) {
+	if _, err := New([]string{"http://example.invalid"}, "xml"); err == nil {
+		t.Error("无效格式应返回错误")
+	}
+}
+
+func TestEventFilter(t *testing.T) {
+	srv := &server{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	n := newNotifier(t, ts.URL, FormatGeneric)
+	ev := lowEvent(100)
+	ev.Type = events.BatteryFull
+	if err := n.HandleEvent(ev); err != nil {
+		t.Fatal(err)
+	}
+	if len(srv.bodies) != 0 {
+		t.Errorf("未订阅的事件不应发送, 收到 %d 个请求", len(srv.bodies))
+	}
+}
+
+func TestRetry(t *testing.T) {
+	srv := &server{status: []int{http.StatusInternalServerError, http.StatusTooManyRequests}}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	n := newNotifier(t, ts.URL, FormatGeneric)
+	var waits []time.Duration
+	n.sleep = func(d time.Duration) { waits = append(waits, d) }
+
+	if err := n.HandleEvent(lowEvent(18)); err != nil {
+		t.Fatal(err)
+	}
+	if len(srv.bodies) != 1 {
+		t.Fatalf("收到 %d 个请求, want 1", len(srv.bodies))
+	}
+	if len(waits) != 2 || waits[0] != DefaultBackoff || waits[1] != 2*DefaultBackoff {
+		t.Errorf("waits = %v, want [1s 2s]", waits)
+	}
+	if _, err := os.Stat(n.QueuePath); !os.IsNotExist(err) {
+		t.Error("送达后不应保留队列文件")
+	}
+}
+
+func TestQueueAfterOutage(t *testing.T) {
+	srv := &server{status: []int{503, 503, 503, 503, 503, 503}}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	n := newNotifier(t, ts.URL, FormatGeneric)
+
+	// 第一次发送用完所有重试，进入队列
+	if err := n.HandleEvent(lowEvent(18)); err == nil {
+		t.Fatal("服务不可用时应返回错误")
+	}
+	queue, err := n.loadQueue()
+	if err != nil || len(queue) != 1 {
+		t.Fatalf("queue = %v, %v", queue, err)
+	}
+
+	// 第二次发送时服务仍不可用，新事件排在队列后面，不再单独尝试
+	ev := lowEvent(9)
+	ev.Type = events.BatteryCritical
+	if err := n.HandleEvent(ev); err == nil {
+		t.Fatal("服务不可用时应返回错误")
+	}
+	if queue, _ := n.loadQueue(); len(queue) != 2 {
+		t.Fatalf("queue 长度 = %d, want 2", len(queue))
+	}
+
+	// 服务恢复后按顺序补发
+	if err := n.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(srv.bodies) != 2 {
+		t.Fatalf("收到 %d 个请求, want 2", len(srv.bodies))
+	}
+	var first, second genericPayload
+	_ = json.Unmarshal(srv.bodies[0], &first)
+	_ = json.Unmarshal(srv.bodies[1], &second)
+	if first.Type != events.BatteryLow || second.Type != events.BatteryCritical {
+		t.Errorf("补发顺序 = %s, %s", first.Type, second.Type)
+	}
+	if _, err := os.Stat(n.QueuePath); !os.IsNotExist(err) {
+		t.Error("补发后不应保留队列文件")
+	}
+}
+
+func TestPermanentError(t *testing.T) {
+	srv := &server{status: []int{http.StatusBadRequest}}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	n := newNotifier(t, ts.URL, FormatGeneric)
+	if err := n.HandleEvent(lowEvent(18)); err == nil {
+		t.Fatal("4xx 应返回错误")
+	}
+	if len(srv.status) != 0 || len(srv.bodies) != 0 {
+		t.Error("4xx 不应重试")
+	}
+	if queue, _ := n.loadQueue(); len(queue) != 0 {
+		t.Errorf("4xx 不应进入队列, queue = %v", queue)
+	}
+}
+
+func TestQueueExpiry(t *testing.T) {
+	srv := &server{status: []int{503, 503, 503}}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	n := newNotifier(t, ts.URL, FormatGeneric)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	n.now = func() time.Time { return now }
+
+	_ = n.HandleEvent(lowEvent(18))
+	now = now.Add(DefaultMaxAge + time.Minute)
+	if err := n.Flush(); err == nil {
+		t.Error("丢弃过期请求时应返回错误")
+	}
+	if len(srv.bodies) != 0 {
+		t.Error("过期请求不应发送")
+	}
+	if queue, _ := n.loadQueue(); len(queue) != 0 {
+		t.Errorf("queue = %v, want empty", queue)
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	n := newNotifier(t, slow.URL, FormatGeneric)
+	n.URLs = append(n.URLs, slow.URL+"/second")
+	n.Deadline = 100 * time.Millisecond
+
+	// 超过总时限后不再等待，两个请求都留在队列中
+	start := time.Now()
+	if err := n.HandleEvent(lowEvent(18)); err == nil {
+		t.Error("超时应返回错误")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("超过总时限后仍在等待，耗时 %s", elapsed)
+	}
+	if queue, _ := n.loadQueue(); len(queue) != 2 {
+		t.Errorf("queue 长度 = %d, want 2", len(queue))
+	}
+}